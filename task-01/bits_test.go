@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go bits_test.go
+
+var ErrInvalidWidth = errors.New("invalid width")
+
+// Integer is a fixed-width integer whose bits can be reversed.
+type Integer interface {
+	~int8 | ~int16 | ~int32 | ~int64 |
+		~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// ReverseBits returns val with the order of its bits reversed,
+// turning an MSB-first value into an LSB-first one and back.
+func ReverseBits[T Integer](val T) T {
+	switch unsafe.Sizeof(val) {
+	case 1:
+		return T(bits.Reverse8(uint8(val)))
+	case 2:
+		return T(bits.Reverse16(uint16(val)))
+	case 4:
+		return T(bits.Reverse32(uint32(val)))
+	default:
+		return T(bits.Reverse64(uint64(val)))
+	}
+}
+
+// SwapBytesN reverses the byte order of every width-byte field packed in b.
+// len(b) must be a multiple of width.
+func SwapBytesN(b []byte, width int) error {
+	if width <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidWidth, width)
+	}
+
+	if len(b)%width != 0 {
+		return fmt.Errorf("%w: length %d is not a multiple of %d", ErrInvalidWidth, len(b), width)
+	}
+
+	for i := 0; i < len(b); i += width {
+		slices.Reverse(b[i : i+width])
+	}
+
+	return nil
+}
+
+func TestReverseBitsUint8(t *testing.T) {
+	tests := map[string]struct {
+		number uint8
+		result uint8
+	}{
+		"test case #1": {number: 0x00, result: 0x00},
+		"test case #2": {number: 0xFF, result: 0xFF},
+		"test case #3": {number: 0x01, result: 0x80},
+		"test case #4": {number: 0x0F, result: 0xF0},
+		"test case #5": {number: 0xB4, result: 0x2D},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, ReverseBits(test.number))
+		})
+	}
+}
+
+func TestReverseBitsUint16(t *testing.T) {
+	tests := map[string]struct {
+		number uint16
+		result uint16
+	}{
+		"test case #1": {number: 0x0000, result: 0x0000},
+		"test case #2": {number: 0x0001, result: 0x8000},
+		"test case #3": {number: 0x00FF, result: 0xFF00},
+		"test case #4": {number: 0x1234, result: 0x2C48},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, ReverseBits(test.number))
+		})
+	}
+}
+
+func TestReverseBitsUint32(t *testing.T) {
+	tests := map[string]struct {
+		number uint32
+		result uint32
+	}{
+		"test case #1": {number: 0x00000000, result: 0x00000000},
+		"test case #2": {number: 0x00000001, result: 0x80000000},
+		"test case #3": {number: 0x04C11DB7, result: 0xEDB88320},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, ReverseBits(test.number))
+		})
+	}
+}
+
+func TestReverseBitsUint64(t *testing.T) {
+	tests := map[string]struct {
+		number uint64
+		result uint64
+	}{
+		"test case #1": {number: 0x0000000000000000, result: 0x0000000000000000},
+		"test case #2": {number: 0x0000000000000001, result: 0x8000000000000000},
+		"test case #3": {number: 0x00000000FFFFFFFF, result: 0xFFFFFFFF00000000},
+		"test case #4": {number: 0x42F0E1EBA9EA3693, result: 0xC96C5795D7870F42},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.result, ReverseBits(test.number))
+		})
+	}
+}
+
+func TestReverseBitsInvolution(t *testing.T) {
+	for _, v := range []uint32{0, 1, 0xDEADBEEF, 0x80000001} {
+		assert.Equal(t, v, ReverseBits(ReverseBits(v)))
+	}
+
+	type flags uint16
+	assert.Equal(t, flags(0x8000), ReverseBits(flags(1)))
+}
+
+func TestReverseBitsSigned(t *testing.T) {
+	assert.Equal(t, int8(-128), ReverseBits(int8(1)))
+	assert.Equal(t, int8(1), ReverseBits(int8(-128)))
+	assert.Equal(t, int16(-1), ReverseBits(int16(-1)))
+	assert.Equal(t, int32(0x0000FFFF), ReverseBits(int32(-0x10000)))
+	assert.Equal(t, int64(-0x8000000000000000), ReverseBits(int64(1)))
+}
+
+func TestSwapBytesN(t *testing.T) {
+	tests := map[string]struct {
+		data   []byte
+		width  int
+		result []byte
+	}{
+		"empty": {
+			data:   []byte{},
+			width:  3,
+			result: []byte{},
+		},
+		"width 1": {
+			data:   []byte{1, 2, 3},
+			width:  1,
+			result: []byte{1, 2, 3},
+		},
+		"24-bit fields": {
+			data:   []byte{0x01, 0x02, 0x03, 0x0A, 0x0B, 0x0C},
+			width:  3,
+			result: []byte{0x03, 0x02, 0x01, 0x0C, 0x0B, 0x0A},
+		},
+		"48-bit fields": {
+			data:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			width:  6,
+			result: []byte{6, 5, 4, 3, 2, 1, 12, 11, 10, 9, 8, 7},
+		},
+		"32-bit fields": {
+			data:   []byte{0x01, 0x02, 0x03, 0x04},
+			width:  4,
+			result: []byte{0x04, 0x03, 0x02, 0x01},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, SwapBytesN(test.data, test.width))
+			assert.Equal(t, test.result, test.data)
+		})
+	}
+}
+
+func TestSwapBytesNErrors(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5}
+
+	assert.ErrorIs(t, SwapBytesN(data, 0), ErrInvalidWidth)
+	assert.ErrorIs(t, SwapBytesN(data, -3), ErrInvalidWidth)
+	assert.ErrorIs(t, SwapBytesN(data, 3), ErrInvalidWidth)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5}, data)
+}
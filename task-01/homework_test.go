@@ -1,6 +1,8 @@
 package main
 
 import (
+	"math"
+	"strconv"
 	"testing"
 	"unsafe"
 
@@ -9,7 +11,96 @@ import (
 
 // go test -v homework_test.go
 
-func ToLittleEndian[T ~uint16 | ~uint32 | ~uint64](val T) T {
+// Word is a fixed-width value whose bytes can be reordered.
+// Converting a single-byte int8 never changes it.
+type Word interface {
+	~int8 | ~int16 | ~int32 | ~int64 |
+		~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		Uint128
+}
+
+// Uint128 keeps Lo first so that its memory layout matches
+// a native 128-bit integer on little-endian hosts.
+type Uint128 struct {
+	Lo uint64
+	Hi uint64
+}
+
+type ByteOrder int
+
+const (
+	BigEndian ByteOrder = iota + 1
+	LittleEndian
+)
+
+// Native is the byte order of the host, detected at init.
+var Native ByteOrder
+
+func init() {
+	probe := uint16(1)
+	if *(*uint8)(unsafe.Pointer(&probe)) == 1 {
+		Native = LittleEndian
+	} else {
+		Native = BigEndian
+	}
+}
+
+func (o ByteOrder) String() string {
+	switch o {
+	case BigEndian:
+		return "BigEndian"
+	case LittleEndian:
+		return "LittleEndian"
+	default:
+		return "ByteOrder(" + strconv.Itoa(int(o)) + ")"
+	}
+}
+
+// resolve returns the order o stands for: the zero ByteOrder means Native.
+// It panics on values other than the declared orders.
+func (o ByteOrder) resolve() ByteOrder {
+	switch o {
+	case 0:
+		return Native
+	case BigEndian, LittleEndian:
+		return o
+	default:
+		panic("invalid byte order " + o.String())
+	}
+}
+
+// Convert reinterprets val, stored in the from order, as a value stored in the to order.
+// The zero ByteOrder means Native.
+func Convert[T Word](val T, from, to ByteOrder) T {
+	if from.resolve() == to.resolve() {
+		return val
+	}
+
+	return swapBytes(val)
+}
+
+func HostToBE[T Word](val T) T {
+	return Convert(val, Native, BigEndian)
+}
+
+func BEToHost[T Word](val T) T {
+	return Convert(val, BigEndian, Native)
+}
+
+func HostToLE[T Word](val T) T {
+	return Convert(val, Native, LittleEndian)
+}
+
+func LEToHost[T Word](val T) T {
+	return Convert(val, LittleEndian, Native)
+}
+
+func ToLittleEndian[T Word](val T) T {
+	return Convert(val, BigEndian, LittleEndian)
+}
+
+func swapBytes[T Word](val T) T {
 	var res T
 
 	valPtr := unsafe.Pointer(&val)
@@ -119,3 +210,324 @@ func TestConversionUint64(t *testing.T) {
 		})
 	}
 }
+
+func TestConvert(t *testing.T) {
+	tests := map[string]struct {
+		number uint32
+		from   ByteOrder
+		to     ByteOrder
+		result uint32
+	}{
+		"big to big": {
+			number: 0x01020304,
+			from:   BigEndian,
+			to:     BigEndian,
+			result: 0x01020304,
+		},
+		"little to little": {
+			number: 0x01020304,
+			from:   LittleEndian,
+			to:     LittleEndian,
+			result: 0x01020304,
+		},
+		"big to little": {
+			number: 0x01020304,
+			from:   BigEndian,
+			to:     LittleEndian,
+			result: 0x04030201,
+		},
+		"little to big": {
+			number: 0x01020304,
+			from:   LittleEndian,
+			to:     BigEndian,
+			result: 0x04030201,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := Convert(test.number, test.from, test.to)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestNative(t *testing.T) {
+	value := uint16(0x0102)
+	first := *(*uint8)(unsafe.Pointer(&value))
+
+	switch Native {
+	case LittleEndian:
+		assert.Equal(t, uint8(0x02), first)
+	case BigEndian:
+		assert.Equal(t, uint8(0x01), first)
+	default:
+		t.Fatalf("unexpected native byte order: %v", Native)
+	}
+}
+
+func TestHostConversions(t *testing.T) {
+	const value = uint64(0x0102030405060708)
+
+	be := HostToBE(value)
+	le := HostToLE(value)
+
+	assert.Equal(t, value, BEToHost(be))
+	assert.Equal(t, value, LEToHost(le))
+
+	if Native == LittleEndian {
+		assert.Equal(t, value, le)
+		assert.Equal(t, uint64(0x0807060504030201), be)
+	} else {
+		assert.Equal(t, value, be)
+		assert.Equal(t, uint64(0x0807060504030201), le)
+	}
+
+	beBytes := *(*[8]byte)(unsafe.Pointer(&be))
+	leBytes := *(*[8]byte)(unsafe.Pointer(&le))
+
+	assert.Equal(t, [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, beBytes)
+	assert.Equal(t, [8]byte{8, 7, 6, 5, 4, 3, 2, 1}, leBytes)
+}
+
+func TestByteOrderString(t *testing.T) {
+	assert.Equal(t, "BigEndian", BigEndian.String())
+	assert.Equal(t, "LittleEndian", LittleEndian.String())
+	assert.Equal(t, "ByteOrder(0)", ByteOrder(0).String())
+}
+
+func TestConvertInvalidOrder(t *testing.T) {
+	const value = uint32(0x01020304)
+
+	assert.Equal(t, value, Convert(value, ByteOrder(0), Native))
+	assert.Equal(t, HostToBE(value), Convert(value, ByteOrder(0), BigEndian))
+	assert.Equal(t, LEToHost(value), Convert(value, LittleEndian, ByteOrder(0)))
+
+	assert.PanicsWithValue(t, "invalid byte order ByteOrder(3)", func() {
+		Convert(value, ByteOrder(3), Native)
+	})
+	assert.Panics(t, func() {
+		Convert(value, BigEndian, ByteOrder(-1))
+	})
+}
+
+func TestConversionInt8(t *testing.T) {
+	for _, number := range []int8{0, 1, -1, math.MinInt8, math.MaxInt8} {
+		assert.Equal(t, number, ToLittleEndian(number))
+		assert.Equal(t, number, HostToBE(number))
+	}
+}
+
+func TestConversionInt16(t *testing.T) {
+	tests := map[string]struct {
+		number int16
+		result int16
+	}{
+		"test case #1": {
+			number: 0x0000,
+			result: 0x0000,
+		},
+		"test case #2": {
+			number: -1,
+			result: -1,
+		},
+		"test case #3": {
+			number: 0x00FF,
+			result: -0x0100,
+		},
+		"test case #4": {
+			number: 0x1234,
+			result: 0x3412,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(test.number)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestConversionInt32(t *testing.T) {
+	tests := map[string]struct {
+		number int32
+		result int32
+	}{
+		"test case #1": {
+			number: 0x00000000,
+			result: 0x00000000,
+		},
+		"test case #2": {
+			number: -1,
+			result: -1,
+		},
+		"test case #3": {
+			number: 0x000000FF,
+			result: -0x01000000,
+		},
+		"test case #4": {
+			number: 0x01020304,
+			result: 0x04030201,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(test.number)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestConversionInt64(t *testing.T) {
+	tests := map[string]struct {
+		number int64
+		result int64
+	}{
+		"test case #1": {
+			number: 0x0000000000000000,
+			result: 0x0000000000000000,
+		},
+		"test case #2": {
+			number: -1,
+			result: -1,
+		},
+		"test case #3": {
+			number: 0x00000000000000FF,
+			result: -0x0100000000000000,
+		},
+		"test case #4": {
+			number: 0x0102030405060708,
+			result: 0x0807060504030201,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(test.number)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestConversionUintptr(t *testing.T) {
+	number := uintptr(0x01020304)
+	result := uintptr(0x04030201)
+
+	if unsafe.Sizeof(number) == 8 {
+		// variables keep the 64-bit values from overflowing uintptr at compile time on 32-bit targets
+		wide, swapped := uint64(0x0102030405060708), uint64(0x0807060504030201)
+		number, result = uintptr(wide), uintptr(swapped)
+	}
+
+	assert.Equal(t, result, ToLittleEndian(number))
+	assert.Equal(t, number, ToLittleEndian(ToLittleEndian(number)))
+}
+
+func TestConversionFloat32(t *testing.T) {
+	tests := map[string]struct {
+		bits   uint32
+		result uint32
+	}{
+		"zero": {
+			bits:   0x00000000,
+			result: 0x00000000,
+		},
+		"one": {
+			bits:   0x3F800000,
+			result: 0x0000803F,
+		},
+		"negative infinity": {
+			bits:   0xFF800000,
+			result: 0x000080FF,
+		},
+		"quiet nan with payload": {
+			bits:   0x7FC01234,
+			result: 0x3412C07F,
+		},
+		"signaling nan with payload": {
+			bits:   0x7F801234,
+			result: 0x3412807F,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(math.Float32frombits(test.bits))
+			assert.Equal(t, test.result, math.Float32bits(result))
+
+			restored := ToLittleEndian(result)
+			assert.Equal(t, test.bits, math.Float32bits(restored))
+		})
+	}
+}
+
+func TestConversionFloat64(t *testing.T) {
+	tests := map[string]struct {
+		bits   uint64
+		result uint64
+	}{
+		"zero": {
+			bits:   0x0000000000000000,
+			result: 0x0000000000000000,
+		},
+		"one": {
+			bits:   0x3FF0000000000000,
+			result: 0x000000000000F03F,
+		},
+		"negative zero": {
+			bits:   0x8000000000000000,
+			result: 0x0000000000000080,
+		},
+		"quiet nan with payload": {
+			bits:   0x7FF8000000ABCDEF,
+			result: 0xEFCDAB000000F87F,
+		},
+		"signaling nan with payload": {
+			bits:   0x7FF0000000000001,
+			result: 0x010000000000F07F,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(math.Float64frombits(test.bits))
+			assert.Equal(t, test.result, math.Float64bits(result))
+
+			restored := ToLittleEndian(result)
+			assert.Equal(t, test.bits, math.Float64bits(restored))
+		})
+	}
+}
+
+func TestConversionUint128(t *testing.T) {
+	tests := map[string]struct {
+		number Uint128
+		result Uint128
+	}{
+		"test case #1": {
+			number: Uint128{},
+			result: Uint128{},
+		},
+		"test case #2": {
+			number: Uint128{Hi: 0xFFFFFFFFFFFFFFFF, Lo: 0xFFFFFFFFFFFFFFFF},
+			result: Uint128{Hi: 0xFFFFFFFFFFFFFFFF, Lo: 0xFFFFFFFFFFFFFFFF},
+		},
+		"test case #3": {
+			number: Uint128{Hi: 0, Lo: 0xFF},
+			result: Uint128{Hi: 0xFF00000000000000, Lo: 0},
+		},
+		"test case #4": {
+			number: Uint128{Hi: 0x0102030405060708, Lo: 0x090A0B0C0D0E0F10},
+			result: Uint128{Hi: 0x100F0E0D0C0B0A09, Lo: 0x0807060504030201},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := ToLittleEndian(test.number)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
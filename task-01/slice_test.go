@@ -0,0 +1,220 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go slice_test.go
+// go test -bench=. homework_test.go slice_test.go
+
+const wordSize = 8
+
+// ToLittleEndianSlice converts min(len(dst), len(src)) values and returns that count.
+// dst and src must either be the same slice or not overlap.
+func ToLittleEndianSlice[T Word](dst, src []T) int {
+	n := min(len(dst), len(src))
+	if n == 0 {
+		return 0
+	}
+
+	dst, src = dst[:n], src[:n]
+	size := int(unsafe.Sizeof(src[0]))
+
+	i := 0
+	if size > 1 && size < wordSize && alignment(&dst[0]) == alignment(&src[0]) {
+		for ; i < n && alignment(&src[i]) != 0; i++ {
+			dst[i] = reverseBytes(src[i])
+		}
+
+		perWord := wordSize / size
+		if words := (n - i) / perWord; words > 0 {
+			srcWords := unsafe.Slice((*uint64)(unsafe.Pointer(&src[i])), words)
+			dstWords := unsafe.Slice((*uint64)(unsafe.Pointer(&dst[i])), words)
+
+			for j, w := range srcWords {
+				dstWords[j] = reverseLanes(w, size)
+			}
+
+			i += words * perWord
+		}
+	}
+
+	for ; i < n; i++ {
+		dst[i] = reverseBytes(src[i])
+	}
+
+	return n
+}
+
+func ToLittleEndianInPlace[T Word](data []T) {
+	ToLittleEndianSlice(data, data)
+}
+
+func reverseBytes[T Word](val T) T {
+	p := unsafe.Pointer(&val)
+
+	switch unsafe.Sizeof(val) {
+	case 2:
+		*(*uint16)(p) = bits.ReverseBytes16(*(*uint16)(p))
+	case 4:
+		*(*uint32)(p) = bits.ReverseBytes32(*(*uint32)(p))
+	case 8:
+		*(*uint64)(p) = bits.ReverseBytes64(*(*uint64)(p))
+	default:
+		return swapBytes(val)
+	}
+
+	return val
+}
+
+func reverseLanes(w uint64, size int) uint64 {
+	switch size {
+	case 2:
+		const mask = 0x00FF00FF00FF00FF
+		return (w&mask)<<8 | (w>>8)&mask
+	case 4:
+		return bits.RotateLeft64(bits.ReverseBytes64(w), 32)
+	default:
+		return bits.ReverseBytes64(w)
+	}
+}
+
+func alignment[T any](p *T) uintptr {
+	return uintptr(unsafe.Pointer(p)) % wordSize
+}
+
+func TestToLittleEndianSliceInt8(t *testing.T) {
+	src := []int8{1, 2, 3, 4, 5, 6, 7, 8, 9, -1}
+	dst := make([]int8, len(src))
+
+	assert.Equal(t, len(src), ToLittleEndianSlice(dst, src))
+	assert.Equal(t, src, dst)
+}
+
+func TestToLittleEndianSliceUint16(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) uint16 { return uint16(r.Uint32()) })
+}
+
+func TestToLittleEndianSliceUint32(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) uint32 { return r.Uint32() })
+}
+
+func TestToLittleEndianSliceUint64(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) uint64 { return r.Uint64() })
+}
+
+func testToLittleEndianSlice[T Word](t *testing.T, random func(*rand.Rand) T) {
+	r := rand.New(rand.NewSource(1))
+
+	src := make([]T, 67)
+	for i := range src {
+		src[i] = random(r)
+	}
+
+	for offset := 0; offset < 4; offset++ {
+		for length := 0; length <= len(src)-offset; length++ {
+			in := src[offset : offset+length]
+
+			expected := make([]T, length)
+			for i, v := range in {
+				expected[i] = ToLittleEndian(v)
+			}
+
+			dst := make([]T, length)
+			assert.Equal(t, length, ToLittleEndianSlice(dst, in))
+			assert.Equal(t, expected, dst)
+
+			inPlace := make([]T, length)
+			copy(inPlace, in)
+			ToLittleEndianInPlace(inPlace)
+			assert.Equal(t, expected, inPlace)
+		}
+	}
+}
+
+func TestToLittleEndianSliceInt32(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) int32 { return int32(r.Uint32()) })
+}
+
+func TestToLittleEndianSliceFloat64(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) float64 { return r.NormFloat64() })
+}
+
+func TestToLittleEndianSliceUint128(t *testing.T) {
+	testToLittleEndianSlice(t, func(r *rand.Rand) Uint128 { return Uint128{Hi: r.Uint64(), Lo: r.Uint64()} })
+}
+
+func TestToLittleEndianSliceUnaligned(t *testing.T) {
+	src := []uint16{0x0102, 0x0304, 0x0506, 0x0708, 0x090A, 0x0B0C}
+	dst := make([]uint16, len(src)+1)
+
+	assert.Equal(t, len(src), ToLittleEndianSlice(dst[1:], src))
+	assert.Equal(t, []uint16{0, 0x0201, 0x0403, 0x0605, 0x0807, 0x0A09, 0x0C0B}, dst)
+}
+
+func TestToLittleEndianSliceLengths(t *testing.T) {
+	src := []uint32{0x01020304, 0x05060708, 0x090A0B0C}
+
+	short := make([]uint32, 2)
+	assert.Equal(t, 2, ToLittleEndianSlice(short, src))
+	assert.Equal(t, []uint32{0x04030201, 0x08070605}, short)
+
+	long := make([]uint32, 4)
+	assert.Equal(t, 3, ToLittleEndianSlice(long, src))
+	assert.Equal(t, []uint32{0x04030201, 0x08070605, 0x0C0B0A09, 0}, long)
+
+	assert.Equal(t, 0, ToLittleEndianSlice(nil, src))
+	assert.Equal(t, 0, ToLittleEndianSlice(long, nil))
+}
+
+const benchmarkSamples = 1 << 16
+
+func BenchmarkToLittleEndianUint16(b *testing.B) {
+	benchmarkToLittleEndian[uint16](b)
+}
+
+func BenchmarkToLittleEndianUint32(b *testing.B) {
+	benchmarkToLittleEndian[uint32](b)
+}
+
+func BenchmarkToLittleEndianUint64(b *testing.B) {
+	benchmarkToLittleEndian[uint64](b)
+}
+
+func benchmarkToLittleEndian[T ~uint16 | ~uint32 | ~uint64](b *testing.B) {
+	src := make([]T, benchmarkSamples)
+	for i := range src {
+		src[i] = T(i * 0x01010101)
+	}
+
+	dst := make([]T, len(src))
+	bytes := int64(len(src)) * int64(unsafe.Sizeof(src[0]))
+
+	b.Run("PerValue", func(b *testing.B) {
+		b.SetBytes(bytes)
+		for b.Loop() {
+			for i, v := range src {
+				dst[i] = ToLittleEndian(v)
+			}
+		}
+	})
+
+	b.Run("Slice", func(b *testing.B) {
+		b.SetBytes(bytes)
+		for b.Loop() {
+			ToLittleEndianSlice(dst, src)
+		}
+	})
+
+	b.Run("InPlace", func(b *testing.B) {
+		b.SetBytes(bytes)
+		for b.Loop() {
+			ToLittleEndianInPlace(dst)
+		}
+	})
+}
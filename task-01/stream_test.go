@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go stream_test.go
+
+var (
+	ErrInvalidAlignment = errors.New("invalid alignment")
+	ErrInvalidLength    = errors.New("invalid length")
+)
+
+// Reader is not thread-safe. The first error is sticky:
+// after it every read returns zero values and Err reports it.
+type Reader struct {
+	r      io.Reader
+	order  ByteOrder
+	offset int64
+	err    error
+}
+
+// NewReader reads values stored in order; the zero ByteOrder means Native.
+func NewReader(r io.Reader, order ByteOrder) *Reader {
+	return &Reader{r: r, order: order.resolve()}
+}
+
+func ReadValue[T Word](r *Reader) T {
+	var val T
+
+	if !r.fill(valueBytes(&val)) {
+		var zero T
+		return zero
+	}
+
+	return Convert(val, r.order, Native)
+}
+
+func (r *Reader) Uint16() uint16 {
+	return ReadValue[uint16](r)
+}
+
+func (r *Reader) Uint32() uint32 {
+	return ReadValue[uint32](r)
+}
+
+func (r *Reader) Uint64() uint64 {
+	return ReadValue[uint64](r)
+}
+
+// readNChunk bounds the memory ReadN allocates before the data arrives.
+const readNChunk = 64 << 10
+
+// ReadN reads exactly n bytes. The result grows as data arrives,
+// so a corrupt length can't allocate much more than the input holds.
+func (r *Reader) ReadN(n int) []byte {
+	if n < 0 {
+		r.setErr(ErrInvalidLength)
+		return nil
+	}
+
+	if r.err != nil {
+		return nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, min(n, readNChunk)))
+	read, err := io.CopyN(buf, r.r, int64(n))
+	r.offset += read
+
+	if errors.Is(err, io.EOF) && read > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+
+	r.setErr(err)
+	if r.err != nil {
+		return nil
+	}
+
+	return buf.Bytes()
+}
+
+func (r *Reader) Skip(n int) {
+	if n < 0 {
+		r.setErr(ErrInvalidLength)
+		return
+	}
+
+	if r.err != nil {
+		return
+	}
+
+	skipped, err := io.CopyN(io.Discard, r.r, int64(n))
+	r.offset += skipped
+
+	if errors.Is(err, io.EOF) && skipped > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+
+	r.setErr(err)
+}
+
+// Align skips bytes until the offset is a multiple of n.
+func (r *Reader) Align(n int) {
+	if n <= 0 {
+		r.setErr(ErrInvalidAlignment)
+		return
+	}
+
+	r.Skip(padding(r.offset, n))
+}
+
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) fill(buf []byte) bool {
+	if r.err != nil {
+		return false
+	}
+
+	n, err := io.ReadFull(r.r, buf)
+	r.offset += int64(n)
+	r.setErr(err)
+
+	return r.err == nil
+}
+
+func (r *Reader) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// Writer is not thread-safe. The first error is sticky:
+// after it every write is dropped and Err reports it.
+type Writer struct {
+	w      io.Writer
+	order  ByteOrder
+	offset int64
+	err    error
+}
+
+// NewWriter stores values in order; the zero ByteOrder means Native.
+func NewWriter(w io.Writer, order ByteOrder) *Writer {
+	return &Writer{w: w, order: order.resolve()}
+}
+
+func WriteValue[T Word](w *Writer, val T) {
+	val = Convert(val, Native, w.order)
+	_, _ = w.Write(valueBytes(&val))
+}
+
+func (w *Writer) Uint16(val uint16) {
+	WriteValue(w, val)
+}
+
+func (w *Writer) Uint32(val uint32) {
+	WriteValue(w, val)
+}
+
+func (w *Writer) Uint64(val uint64) {
+	WriteValue(w, val)
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	w.setErr(err)
+
+	return n, w.err
+}
+
+// Pad writes n zero bytes.
+func (w *Writer) Pad(n int) {
+	if n < 0 {
+		w.setErr(ErrInvalidLength)
+		return
+	}
+
+	_, _ = w.Write(make([]byte, n))
+}
+
+// Align pads with zero bytes until the offset is a multiple of n.
+func (w *Writer) Align(n int) {
+	if n <= 0 {
+		w.setErr(ErrInvalidAlignment)
+		return
+	}
+
+	w.Pad(padding(w.offset, n))
+}
+
+func (w *Writer) Offset() int64 {
+	return w.offset
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func valueBytes[T Word](val *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(val)), unsafe.Sizeof(*val))
+}
+
+func padding(offset int64, align int) int {
+	return int((int64(align) - offset%int64(align)) % int64(align))
+}
+
+func TestReader(t *testing.T) {
+	data := []byte{
+		0x01, 0x02,
+		0x01, 0x02, 0x03, 0x04,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	}
+
+	tests := map[string]struct {
+		order ByteOrder
+		u16   uint16
+		u32   uint32
+		u64   uint64
+	}{
+		"big endian": {
+			order: BigEndian,
+			u16:   0x0102,
+			u32:   0x01020304,
+			u64:   0x0102030405060708,
+		},
+		"little endian": {
+			order: LittleEndian,
+			u16:   0x0201,
+			u32:   0x04030201,
+			u64:   0x0807060504030201,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data), test.order)
+
+			assert.Equal(t, test.u16, r.Uint16())
+			assert.Equal(t, test.u32, r.Uint32())
+			assert.Equal(t, test.u64, r.Uint64())
+			assert.NoError(t, r.Err())
+			assert.Equal(t, int64(len(data)), r.Offset())
+		})
+	}
+}
+
+func TestReaderStickyError(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}), BigEndian)
+
+	assert.Equal(t, uint16(0x0102), r.Uint16())
+	assert.Zero(t, r.Uint32())
+	assert.ErrorIs(t, r.Err(), io.ErrUnexpectedEOF)
+
+	assert.Zero(t, r.Uint16())
+	assert.Nil(t, r.ReadN(1))
+	assert.ErrorIs(t, r.Err(), io.ErrUnexpectedEOF)
+	assert.Equal(t, int64(3), r.Offset())
+}
+
+func TestReaderEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader(nil), LittleEndian)
+
+	assert.Zero(t, r.Uint64())
+	assert.ErrorIs(t, r.Err(), io.EOF)
+}
+
+func TestReaderReadNAndAlign(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 0, 0, 0, 0, 0, 0x01, 0x00, 0x00, 0x00}
+	r := NewReader(bytes.NewReader(data), LittleEndian)
+
+	assert.Equal(t, []byte("abc"), r.ReadN(3))
+	r.Align(8)
+	assert.Equal(t, int64(8), r.Offset())
+	r.Align(4)
+	assert.Equal(t, int64(8), r.Offset())
+	assert.Equal(t, uint32(1), r.Uint32())
+	assert.Equal(t, []byte{}, r.ReadN(0))
+	assert.NoError(t, r.Err())
+
+	r.Align(0)
+	assert.ErrorIs(t, r.Err(), ErrInvalidAlignment)
+}
+
+func TestReaderInvalidLength(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{1, 2, 3}), LittleEndian)
+
+	assert.Nil(t, r.ReadN(-1))
+	assert.ErrorIs(t, r.Err(), ErrInvalidLength)
+}
+
+func TestReaderReadNCorruptLength(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{1, 2, 3}), LittleEndian)
+
+	assert.NotPanics(t, func() {
+		assert.Nil(t, r.ReadN(math.MaxInt))
+	})
+	assert.ErrorIs(t, r.Err(), io.ErrUnexpectedEOF)
+	assert.Equal(t, int64(3), r.Offset())
+
+	empty := NewReader(bytes.NewReader(nil), LittleEndian)
+	assert.Nil(t, empty.ReadN(4))
+	assert.ErrorIs(t, empty.Err(), io.EOF)
+
+	large := bytes.Repeat([]byte{0xAB}, 3*readNChunk+1)
+	r = NewReader(bytes.NewReader(large), LittleEndian)
+	assert.Equal(t, large, r.ReadN(len(large)))
+	assert.NoError(t, r.Err())
+}
+
+func TestWriter(t *testing.T) {
+	tests := map[string]struct {
+		order  ByteOrder
+		result []byte
+	}{
+		"big endian": {
+			order: BigEndian,
+			result: []byte{
+				0x01, 0x02,
+				0x00, 0x00,
+				0x01, 0x02, 0x03, 0x04,
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				'x', 0x00, 0x00, 0x00,
+			},
+		},
+		"little endian": {
+			order: LittleEndian,
+			result: []byte{
+				0x02, 0x01,
+				0x00, 0x00,
+				0x04, 0x03, 0x02, 0x01,
+				0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+				'x', 0x00, 0x00, 0x00,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, test.order)
+
+			w.Uint16(0x0102)
+			w.Align(4)
+			w.Uint32(0x01020304)
+			w.Uint64(0x0102030405060708)
+			_, _ = w.Write([]byte{'x'})
+			w.Pad(3)
+
+			assert.NoError(t, w.Err())
+			assert.Equal(t, int64(len(test.result)), w.Offset())
+			assert.Equal(t, test.result, buf.Bytes())
+		})
+	}
+}
+
+type limitedWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		n, _ := w.buf.Write(p[:w.limit-w.buf.Len()])
+		return n, io.ErrShortWrite
+	}
+
+	return w.buf.Write(p)
+}
+
+func TestWriterStickyError(t *testing.T) {
+	lw := &limitedWriter{limit: 5}
+	w := NewWriter(lw, BigEndian)
+
+	w.Uint32(0x01020304)
+	w.Uint32(0x05060708)
+	assert.ErrorIs(t, w.Err(), io.ErrShortWrite)
+
+	w.Uint16(0x0909)
+	n, err := w.Write([]byte{1})
+	assert.Zero(t, n)
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04, 0x05}, lw.buf.Bytes())
+	assert.Equal(t, int64(5), w.Offset())
+}
+
+func TestReaderWriterRoundTrip(t *testing.T) {
+	for _, order := range []ByteOrder{BigEndian, LittleEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, order)
+
+			WriteValue(w, int16(-2))
+			WriteValue(w, float64(3.5))
+			WriteValue(w, Uint128{Hi: 1, Lo: 2})
+			assert.NoError(t, w.Err())
+
+			r := NewReader(&buf, order)
+
+			assert.Equal(t, int16(-2), ReadValue[int16](r))
+			assert.Equal(t, float64(3.5), ReadValue[float64](r))
+			assert.Equal(t, Uint128{Hi: 1, Lo: 2}, ReadValue[Uint128](r))
+			assert.NoError(t, r.Err())
+		})
+	}
+}
+
+func TestWriterUint128(t *testing.T) {
+	if Native != LittleEndian {
+		t.Skip("Uint128 layout matches a native integer only on little-endian hosts")
+	}
+
+	value := Uint128{Hi: 0x0102030405060708, Lo: 0x090A0B0C0D0E0F10}
+
+	var buf bytes.Buffer
+	WriteValue(NewWriter(&buf, BigEndian), value)
+
+	assert.Equal(t, []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10,
+	}, buf.Bytes())
+}
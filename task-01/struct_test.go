@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go slice_test.go struct_test.go
+
+var (
+	ErrNotStructPointer = errors.New("not a pointer to struct")
+	ErrUnsupportedKind  = errors.New("unsupported kind")
+	ErrInvalidEndianTag = errors.New("invalid endian tag")
+)
+
+const endianTag = "endian"
+
+type swapSpan struct {
+	offset uintptr
+	size   uintptr
+}
+
+// swapError names the field SwapStruct cannot convert. Its path is prepended
+// while the error unwinds, so a valid struct builds no strings at all.
+type swapError struct {
+	err    error
+	path   string
+	detail string
+}
+
+func (e *swapError) Error() string {
+	return e.err.Error() + ": " + e.path + " " + e.detail
+}
+
+func (e *swapError) Unwrap() error {
+	return e.err
+}
+
+func prependPath(err error, segment string) error {
+	if e, ok := err.(*swapError); ok {
+		e.path = segment + e.path
+	}
+
+	return err
+}
+
+// SwapStruct converts every numeric field of the struct pointed to by ptr
+// from its wire order to Native in place. Fields are big-endian unless tagged
+// with `endian:"little"`; `endian:"skip"` leaves a field untouched. Tags on
+// nested structs and arrays apply to all their elements. The struct is only
+// modified if every field is supported.
+func SwapStruct(ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrNotStructPointer, ptr)
+	}
+
+	var spans []swapSpan
+	if err := collectSwaps(v.Elem().Type(), 0, BigEndian, &spans); err != nil {
+		return prependPath(err, v.Elem().Type().Name())
+	}
+
+	base := v.UnsafePointer()
+	for _, span := range spans {
+		swapInPlace(unsafe.Add(base, span.offset), span.size)
+	}
+
+	return nil
+}
+
+func collectSwaps(typ reflect.Type, offset uintptr, order ByteOrder, spans *[]swapSpan) error {
+	switch typ.Kind() {
+	case reflect.Struct:
+		if typ == reflect.TypeFor[Uint128]() {
+			break
+		}
+
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			fieldOrder := order
+			switch tag := field.Tag.Get(endianTag); tag {
+			case "":
+			case "big":
+				fieldOrder = BigEndian
+			case "little":
+				fieldOrder = LittleEndian
+			case "skip":
+				continue
+			default:
+				return &swapError{err: ErrInvalidEndianTag, path: "." + field.Name, detail: "has tag " + strconv.Quote(tag)}
+			}
+
+			if err := collectSwaps(field.Type, offset+field.Offset, fieldOrder, spans); err != nil {
+				return prependPath(err, "."+field.Name)
+			}
+		}
+
+		return nil
+	case reflect.Array:
+		if typ.Len() == 0 {
+			return nil
+		}
+
+		// Every element has the layout of the first one, so its spans are
+		// collected once and repeated; arrays of bytes produce none at all.
+		first := len(*spans)
+		if err := collectSwaps(typ.Elem(), offset, order, spans); err != nil {
+			return prependPath(err, "[0]")
+		}
+
+		elemSpans := len(*spans) - first
+		for i := 1; i < typ.Len() && elemSpans > 0; i++ {
+			shift := uintptr(i) * typ.Elem().Size()
+			for j := first; j < first+elemSpans; j++ {
+				span := (*spans)[j]
+				*spans = append(*spans, swapSpan{offset: span.offset + shift, size: span.size})
+			}
+		}
+
+		return nil
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+	default:
+		return &swapError{err: ErrUnsupportedKind, detail: "has kind " + typ.Kind().String()}
+	}
+
+	if order != Native && typ.Size() > 1 {
+		*spans = append(*spans, swapSpan{offset: offset, size: typ.Size()})
+	}
+
+	return nil
+}
+
+func swapInPlace(p unsafe.Pointer, size uintptr) {
+	switch size {
+	case 2:
+		*(*uint16)(p) = reverseBytes(*(*uint16)(p))
+	case 4:
+		*(*uint32)(p) = reverseBytes(*(*uint32)(p))
+	case 8:
+		*(*uint64)(p) = reverseBytes(*(*uint64)(p))
+	default:
+		slices.Reverse(unsafe.Slice((*byte)(p), size))
+	}
+}
+
+type deviceHeader struct {
+	Magic   uint32
+	Version uint16
+	Flags   uint8
+	Enabled bool
+}
+
+type deviceFrame struct {
+	Header   deviceHeader
+	Samples  [3]int16
+	Scale    float32
+	ID       Uint128
+	Checksum uint32 `endian:"little"`
+	Reserved uint64 `endian:"skip"`
+	Local    struct {
+		Counter uint16
+	} `endian:"little"`
+	Remote struct {
+		Counter uint16 `endian:"big"`
+	} `endian:"little"`
+}
+
+func TestSwapStruct(t *testing.T) {
+	frame := deviceFrame{
+		Header: deviceHeader{
+			Magic:   HostToBE(uint32(0xCAFEBABE)),
+			Version: HostToBE(uint16(2)),
+			Flags:   0x81,
+			Enabled: true,
+		},
+		Samples:  [3]int16{HostToBE(int16(-1)), HostToBE(int16(2)), HostToBE(int16(-300))},
+		Scale:    HostToBE(float32(1.5)),
+		ID:       HostToBE(Uint128{Hi: 1, Lo: 2}),
+		Checksum: HostToLE(uint32(0x01020304)),
+		Reserved: 0x0102030405060708,
+	}
+	frame.Local.Counter = HostToLE(uint16(7))
+	frame.Remote.Counter = HostToBE(uint16(9))
+
+	assert.NoError(t, SwapStruct(&frame))
+
+	assert.Equal(t, uint32(0xCAFEBABE), frame.Header.Magic)
+	assert.Equal(t, uint16(2), frame.Header.Version)
+	assert.Equal(t, uint8(0x81), frame.Header.Flags)
+	assert.True(t, frame.Header.Enabled)
+	assert.Equal(t, [3]int16{-1, 2, -300}, frame.Samples)
+	assert.Equal(t, float32(1.5), frame.Scale)
+	assert.Equal(t, Uint128{Hi: 1, Lo: 2}, frame.ID)
+	assert.Equal(t, uint32(0x01020304), frame.Checksum)
+	assert.Equal(t, uint64(0x0102030405060708), frame.Reserved)
+	assert.Equal(t, uint16(7), frame.Local.Counter)
+	assert.Equal(t, uint16(9), frame.Remote.Counter)
+}
+
+func TestSwapStructUnexportedFields(t *testing.T) {
+	value := struct {
+		a uint16
+		b [2]uint32
+	}{
+		a: HostToBE(uint16(0x0102)),
+		b: [2]uint32{HostToBE(uint32(1)), HostToBE(uint32(2))},
+	}
+
+	assert.NoError(t, SwapStruct(&value))
+	assert.Equal(t, uint16(0x0102), value.a)
+	assert.Equal(t, [2]uint32{1, 2}, value.b)
+}
+
+func TestSwapStructErrors(t *testing.T) {
+	type withString struct {
+		Length uint16
+		Name   string
+	}
+
+	type withNestedSlice struct {
+		Length uint16
+		Inner  struct {
+			Data []byte
+		}
+	}
+
+	type withSkippedString struct {
+		Length uint16
+		Name   string `endian:"skip"`
+	}
+
+	type withInvalidTag struct {
+		Length uint16 `endian:"middle"`
+	}
+
+	var value uint32
+
+	tests := map[string]struct {
+		ptr any
+		err error
+	}{
+		"nil":                {ptr: nil, err: ErrNotStructPointer},
+		"not a pointer":      {ptr: withString{}, err: ErrNotStructPointer},
+		"nil pointer":        {ptr: (*withString)(nil), err: ErrNotStructPointer},
+		"pointer to integer": {ptr: &value, err: ErrNotStructPointer},
+		"string field":       {ptr: &withString{Length: 0x0100}, err: ErrUnsupportedKind},
+		"nested slice":       {ptr: &withNestedSlice{Length: 0x0100}, err: ErrUnsupportedKind},
+		"invalid tag":        {ptr: &withInvalidTag{Length: 0x0100}, err: ErrInvalidEndianTag},
+		"skipped string":     {ptr: &withSkippedString{Length: 0x0100}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := SwapStruct(test.ptr)
+			if test.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestSwapStructErrorPath(t *testing.T) {
+	type inner struct {
+		Values [2]struct {
+			Name string
+		}
+	}
+
+	value := struct {
+		Inner inner
+	}{}
+
+	assert.EqualError(t, SwapStruct(&value), "unsupported kind: .Inner.Values[0].Name has kind string")
+
+	tagged := struct {
+		Items [2]struct {
+			Count uint16 `endian:"middle"`
+		}
+	}{}
+
+	assert.EqualError(t, SwapStruct(&tagged), `invalid endian tag: .Items[0].Count has tag "middle"`)
+}
+
+func TestSwapStructArrays(t *testing.T) {
+	type record struct {
+		Pairs [3][2]uint16
+		Mixed [2]struct {
+			A uint32
+			B uint8
+		}
+	}
+
+	value := record{
+		Pairs: [3][2]uint16{{HostToBE(uint16(1)), HostToBE(uint16(2))}, {HostToBE(uint16(3)), HostToBE(uint16(4))}, {HostToBE(uint16(5)), HostToBE(uint16(6))}},
+	}
+	value.Mixed[0].A, value.Mixed[0].B = HostToBE(uint32(7)), 8
+	value.Mixed[1].A, value.Mixed[1].B = HostToBE(uint32(9)), 10
+
+	assert.NoError(t, SwapStruct(&value))
+	assert.Equal(t, [3][2]uint16{{1, 2}, {3, 4}, {5, 6}}, value.Pairs)
+	assert.Equal(t, uint32(7), value.Mixed[0].A)
+	assert.Equal(t, uint8(8), value.Mixed[0].B)
+	assert.Equal(t, uint32(9), value.Mixed[1].A)
+	assert.Equal(t, uint8(10), value.Mixed[1].B)
+}
+
+type payloadFrame struct {
+	Length  uint32
+	Payload [4096]byte
+	Trailer uint16
+}
+
+func TestSwapStructAllocations(t *testing.T) {
+	var frame payloadFrame
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = SwapStruct(&frame)
+	})
+	assert.LessOrEqual(t, allocs, 2.0)
+}
+
+func BenchmarkSwapStruct(b *testing.B) {
+	var frame payloadFrame
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_ = SwapStruct(&frame)
+	}
+}
+
+func TestSwapStructLeavesInvalidStructUntouched(t *testing.T) {
+	value := struct {
+		Length uint16
+		Name   string
+	}{Length: 0x0102, Name: "abc"}
+
+	assert.ErrorIs(t, SwapStruct(&value), ErrUnsupportedKind)
+	assert.Equal(t, uint16(0x0102), value.Length)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go stream_test.go varint_test.go
+// go test homework_test.go stream_test.go varint_test.go -fuzz='^FuzzUvarint$'
+
+var (
+	ErrVarintOverflow  = errors.New("varint overflows target type")
+	ErrVarintTruncated = errors.New("varint truncated")
+)
+
+type Unsigned interface {
+	~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+type Signed interface {
+	~int16 | ~int32 | ~int64
+}
+
+func MaxVarintLen[T Unsigned | Signed]() int {
+	var zero T
+	return (int(unsafe.Sizeof(zero))*8 + 6) / 7
+}
+
+// PutUvarint encodes v as LEB128 into buf and returns the number of bytes written.
+// It panics if buf is too small; MaxVarintLen bytes are always enough.
+func PutUvarint[T Unsigned](buf []byte, v T) int {
+	return putUvarint(buf, uint64(v))
+}
+
+// Uvarint decodes a LEB128 value from buf and returns it with the number of bytes read.
+func Uvarint[T Unsigned](buf []byte) (T, int, error) {
+	var zero T
+
+	x, n, err := uvarint(buf, uint(unsafe.Sizeof(zero))*8)
+	if err != nil {
+		return zero, 0, err
+	}
+
+	return T(x), n, nil
+}
+
+// PutVarint zigzag-encodes v as LEB128 into buf and returns the number of bytes written.
+// It panics if buf is too small; MaxVarintLen bytes are always enough.
+func PutVarint[T Signed](buf []byte, v T) int {
+	x := int64(v)
+	return putUvarint(buf, uint64(x<<1)^uint64(x>>63))
+}
+
+// Varint decodes a zigzag LEB128 value from buf and returns it with the number of bytes read.
+func Varint[T Signed](buf []byte) (T, int, error) {
+	var zero T
+
+	ux, n, err := uvarint(buf, uint(unsafe.Sizeof(zero))*8)
+	if err != nil {
+		return zero, 0, err
+	}
+
+	return T(int64(ux>>1) ^ -int64(ux&1)), n, nil
+}
+
+func putUvarint(buf []byte, x uint64) int {
+	i := 0
+	for x >= 0x80 {
+		buf[i] = byte(x) | 0x80
+		x >>= 7
+		i++
+	}
+
+	buf[i] = byte(x)
+
+	return i + 1
+}
+
+func uvarint(buf []byte, bits uint) (uint64, int, error) {
+	maxLen := int(bits+6) / 7
+
+	var x uint64
+	var shift uint
+
+	for i, b := range buf {
+		if i == maxLen {
+			return 0, 0, ErrVarintOverflow
+		}
+
+		payload := uint64(b & 0x7F)
+		if shift+7 > bits && payload>>(bits-shift) != 0 {
+			return 0, 0, ErrVarintOverflow
+		}
+
+		x |= payload << shift
+		if b < 0x80 {
+			return x, i + 1, nil
+		}
+
+		shift += 7
+	}
+
+	return 0, 0, ErrVarintTruncated
+}
+
+func TestUvarint(t *testing.T) {
+	tests := map[string]struct {
+		number  uint64
+		encoded []byte
+	}{
+		"zero":          {number: 0, encoded: []byte{0x00}},
+		"one byte":      {number: 0x7F, encoded: []byte{0x7F}},
+		"two bytes":     {number: 0x80, encoded: []byte{0x80, 0x01}},
+		"leb128 sample": {number: 624485, encoded: []byte{0xE5, 0x8E, 0x26}},
+		"max uint64": {
+			number:  math.MaxUint64,
+			encoded: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := make([]byte, MaxVarintLen[uint64]())
+			n := PutUvarint(buf, test.number)
+			assert.Equal(t, test.encoded, buf[:n])
+
+			result, read, err := Uvarint[uint64](test.encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, len(test.encoded), read)
+			assert.Equal(t, test.number, result)
+		})
+	}
+}
+
+func TestVarint(t *testing.T) {
+	tests := map[string]struct {
+		number  int64
+		encoded []byte
+	}{
+		"zero":             {number: 0, encoded: []byte{0x00}},
+		"minus one":        {number: -1, encoded: []byte{0x01}},
+		"one":              {number: 1, encoded: []byte{0x02}},
+		"minus sixty-five": {number: -65, encoded: []byte{0x81, 0x01}},
+		"max int64": {
+			number:  math.MaxInt64,
+			encoded: []byte{0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01},
+		},
+		"min int64": {
+			number:  math.MinInt64,
+			encoded: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := make([]byte, MaxVarintLen[int64]())
+			n := PutVarint(buf, test.number)
+			assert.Equal(t, test.encoded, buf[:n])
+
+			result, read, err := Varint[int64](test.encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, len(test.encoded), read)
+			assert.Equal(t, test.number, result)
+		})
+	}
+}
+
+func TestVarintNarrowTypes(t *testing.T) {
+	buf := make([]byte, MaxVarintLen[int16]())
+
+	n := PutVarint(buf, int16(math.MinInt16))
+	result16, read, err := Varint[int16](buf[:n])
+	assert.NoError(t, err)
+	assert.Equal(t, n, read)
+	assert.Equal(t, int16(math.MinInt16), result16)
+
+	n = PutUvarint(buf, uint16(math.MaxUint16))
+	assert.Equal(t, 3, n)
+	resultU16, _, err := Uvarint[uint16](buf[:n])
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(math.MaxUint16), resultU16)
+
+	assert.Equal(t, 3, MaxVarintLen[uint16]())
+	assert.Equal(t, 5, MaxVarintLen[int32]())
+	assert.Equal(t, 10, MaxVarintLen[uint64]())
+}
+
+func TestVarintErrors(t *testing.T) {
+	tests := map[string]struct {
+		decode func([]byte) error
+		input  []byte
+		err    error
+	}{
+		"empty": {
+			decode: decodeErr(Uvarint[uint64]),
+			input:  nil,
+			err:    ErrVarintTruncated,
+		},
+		"truncated": {
+			decode: decodeErr(Uvarint[uint32]),
+			input:  []byte{0x80, 0x80},
+			err:    ErrVarintTruncated,
+		},
+		"uint16 overflow by value": {
+			decode: decodeErr(Uvarint[uint16]),
+			input:  []byte{0x80, 0x80, 0x04},
+			err:    ErrVarintOverflow,
+		},
+		"uint16 overflow by length": {
+			decode: decodeErr(Uvarint[uint16]),
+			input:  []byte{0x80, 0x80, 0x80, 0x00},
+			err:    ErrVarintOverflow,
+		},
+		"uint64 overflow by value": {
+			decode: decodeErr(Uvarint[uint64]),
+			input:  []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x02},
+			err:    ErrVarintOverflow,
+		},
+		"uint64 overflow by length": {
+			decode: decodeErr(Uvarint[uint64]),
+			input:  []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x81, 0x00},
+			err:    ErrVarintOverflow,
+		},
+		"int32 overflow": {
+			decode: decodeErr(Varint[int32]),
+			input:  []byte{0x80, 0x80, 0x80, 0x80, 0x10},
+			err:    ErrVarintOverflow,
+		},
+		"int16 truncated": {
+			decode: decodeErr(Varint[int16]),
+			input:  []byte{0xFF},
+			err:    ErrVarintTruncated,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, test.decode(test.input), test.err)
+		})
+	}
+}
+
+func decodeErr[T any](decode func([]byte) (T, int, error)) func([]byte) error {
+	return func(buf []byte) error {
+		_, _, err := decode(buf)
+		return err
+	}
+}
+
+func FuzzUvarint(f *testing.F) {
+	f.Add(uint64(0))
+	f.Add(uint64(0x7F))
+	f.Add(uint64(0x80))
+	f.Add(uint64(math.MaxUint16 + 1))
+	f.Add(uint64(math.MaxUint64))
+
+	f.Fuzz(func(t *testing.T, number uint64) {
+		var fixed bytes.Buffer
+		NewWriter(&fixed, LittleEndian).Uint64(number)
+
+		value := NewReader(bytes.NewReader(fixed.Bytes()), LittleEndian).Uint64()
+
+		buf := make([]byte, MaxVarintLen[uint64]())
+		n := PutUvarint(buf, value)
+
+		decoded, read, err := Uvarint[uint64](buf[:n])
+		if err != nil || read != n {
+			t.Fatalf("decode %x: read=%d, err=%v", buf[:n], read, err)
+		}
+
+		var refixed bytes.Buffer
+		NewWriter(&refixed, LittleEndian).Uint64(decoded)
+
+		if !bytes.Equal(fixed.Bytes(), refixed.Bytes()) {
+			t.Fatalf("round trip mismatch: %x != %x", fixed.Bytes(), refixed.Bytes())
+		}
+
+		narrow, _, err := Uvarint[uint16](buf[:n])
+		if number > math.MaxUint16 {
+			if !errors.Is(err, ErrVarintOverflow) {
+				t.Fatalf("expected overflow for %d as uint16, got %v", number, err)
+			}
+		} else if err != nil || uint64(narrow) != number {
+			t.Fatalf("uint16 decode of %d: got %d, err=%v", number, narrow, err)
+		}
+
+		if _, _, err := Uvarint[uint64](buf[:n-1]); !errors.Is(err, ErrVarintTruncated) {
+			t.Fatalf("expected truncation for %x, got %v", buf[:n-1], err)
+		}
+	})
+}
+
+func FuzzVarint(f *testing.F) {
+	f.Add(int64(0))
+	f.Add(int64(-1))
+	f.Add(int64(math.MinInt32))
+	f.Add(int64(math.MaxInt64))
+	f.Add(int64(math.MinInt64))
+
+	f.Fuzz(func(t *testing.T, number int64) {
+		var fixed bytes.Buffer
+		WriteValue(NewWriter(&fixed, BigEndian), number)
+
+		buf := make([]byte, MaxVarintLen[int64]())
+		n := PutVarint(buf, number)
+
+		decoded, read, err := Varint[int64](buf[:n])
+		if err != nil || read != n {
+			t.Fatalf("decode %x: read=%d, err=%v", buf[:n], read, err)
+		}
+
+		var refixed bytes.Buffer
+		WriteValue(NewWriter(&refixed, BigEndian), decoded)
+
+		if !bytes.Equal(fixed.Bytes(), refixed.Bytes()) {
+			t.Fatalf("round trip mismatch: %x != %x", fixed.Bytes(), refixed.Bytes())
+		}
+
+		narrow, _, err := Varint[int32](buf[:n])
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			if !errors.Is(err, ErrVarintOverflow) {
+				t.Fatalf("expected overflow for %d as int32, got %v", number, err)
+			}
+		} else if err != nil || int64(narrow) != number {
+			t.Fatalf("int32 decode of %d: got %d, err=%v", number, narrow, err)
+		}
+	})
+}
+
+func FuzzUvarintDecode(f *testing.F) {
+	f.Add([]byte{0x80, 0x01})
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, n, err := Uvarint[uint64](data)
+		if err != nil {
+			return
+		}
+
+		buf := make([]byte, MaxVarintLen[uint64]())
+		m := PutUvarint(buf, value)
+
+		if m > n {
+			t.Fatalf("canonical encoding of %d is longer than input: %d > %d", value, m, n)
+		}
+
+		again, _, err := Uvarint[uint64](buf[:m])
+		if err != nil || again != value {
+			t.Fatalf("re-decode of %d: got %d, err=%v", value, again, err)
+		}
+	})
+}
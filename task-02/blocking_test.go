@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go blocking_test.go
+
+var ErrQueueClosed = errors.New("queue closed")
+
+// BlockingQueue is a thread-safe CircularQueue whose PushCtx and PopCtx
+// wait while the queue is full or empty.
+type BlockingQueue[T any] struct {
+	mu      sync.Mutex
+	queue   CircularQueue[T]
+	changed chan struct{}
+	closed  bool
+}
+
+func NewBlockingQueue[T any](size int) *BlockingQueue[T] {
+	return &BlockingQueue[T]{
+		queue:   NewCircularQueue[T](size),
+		changed: make(chan struct{}),
+	}
+}
+
+// PushCtx waits until there is room for value, the context is done or the queue is closed.
+func (q *BlockingQueue[T]) PushCtx(ctx context.Context, value T) error {
+	for {
+		q.mu.Lock()
+
+		if q.closed {
+			q.mu.Unlock()
+			return ErrQueueClosed
+		}
+
+		if q.queue.Push(value) {
+			q.notifyLocked()
+			q.mu.Unlock()
+			return nil
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// PopCtx waits until a value is available, the context is done or the queue is closed.
+// Values pushed before Close are still returned.
+func (q *BlockingQueue[T]) PopCtx(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+
+		if value, ok := q.queue.Pop(); ok {
+			q.notifyLocked()
+			q.mu.Unlock()
+			return value, nil
+		}
+
+		if q.closed {
+			q.mu.Unlock()
+
+			var zero T
+			return zero, ErrQueueClosed
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func (q *BlockingQueue[T]) TryPush(value T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || !q.queue.Push(value) {
+		return false
+	}
+
+	q.notifyLocked()
+
+	return true
+}
+
+func (q *BlockingQueue[T]) TryPop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	value, ok := q.queue.Pop()
+	if ok {
+		q.notifyLocked()
+	}
+
+	return value, ok
+}
+
+func (q *BlockingQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.queue.Len()
+}
+
+// Close rejects further pushes and wakes every waiter. It is safe to call more than once.
+func (q *BlockingQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	q.notifyLocked()
+}
+
+func (q *BlockingQueue[T]) notifyLocked() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func TestBlockingQueueTry(t *testing.T) {
+	queue := NewBlockingQueue[int](2)
+
+	assertNotFound[int](t)(queue.TryPop())
+
+	assert.True(t, queue.TryPush(1))
+	assert.True(t, queue.TryPush(2))
+	assert.False(t, queue.TryPush(3))
+	assert.Equal(t, 2, queue.Len())
+
+	assertFound(t, 1)(queue.TryPop())
+	assertFound(t, 2)(queue.TryPop())
+	assertNotFound[int](t)(queue.TryPop())
+}
+
+func TestBlockingQueuePushWaitsForRoom(t *testing.T) {
+	queue := NewBlockingQueue[int](1)
+	assert.True(t, queue.TryPush(1))
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- queue.PushCtx(context.Background(), 2)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push must wait while the queue is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	assertFound(t, 1)(queue.TryPop())
+	assert.NoError(t, <-pushed)
+	assertFound(t, 2)(queue.TryPop())
+}
+
+func TestBlockingQueuePopWaitsForValue(t *testing.T) {
+	queue := NewBlockingQueue[string](1)
+
+	type result struct {
+		value string
+		err   error
+	}
+
+	popped := make(chan result)
+	go func() {
+		value, err := queue.PopCtx(context.Background())
+		popped <- result{value: value, err: err}
+	}()
+
+	select {
+	case <-popped:
+		t.Fatal("pop must wait while the queue is empty")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	assert.NoError(t, queue.PushCtx(context.Background(), "value"))
+	assert.Equal(t, result{value: "value"}, <-popped)
+}
+
+func TestBlockingQueueContextCancel(t *testing.T) {
+	queue := NewBlockingQueue[int](1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := queue.PopCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.True(t, queue.TryPush(1))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, queue.PushCtx(ctx, 2), context.Canceled)
+	assertFound(t, 1)(queue.TryPop())
+}
+
+func TestBlockingQueueCloseWakesWaiters(t *testing.T) {
+	const waiters = 8
+
+	empty := NewBlockingQueue[int](1)
+	full := NewBlockingQueue[int](1)
+	assert.True(t, full.TryPush(0))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*waiters)
+
+	for range waiters {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := empty.PopCtx(context.Background())
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			errs <- full.PushCtx(context.Background(), 1)
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	empty.Close()
+	full.Close()
+	full.Close()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.ErrorIs(t, err, ErrQueueClosed)
+	}
+
+	assert.False(t, full.TryPush(1))
+	assertFound(t, 0)(full.TryPop())
+
+	_, err := full.PopCtx(context.Background())
+	assert.ErrorIs(t, err, ErrQueueClosed)
+}
+
+func TestBlockingQueueConcurrent(t *testing.T) {
+	const producers = 8
+	const consumers = 8
+	const perProducer = 2000
+
+	queue := NewBlockingQueue[int](16)
+	ctx := context.Background()
+
+	var produced sync.WaitGroup
+	for p := range producers {
+		produced.Add(1)
+
+		go func() {
+			defer produced.Done()
+
+			for i := range perProducer {
+				value := p*perProducer + i
+				if i%2 == 0 {
+					assert.NoError(t, queue.PushCtx(ctx, value))
+					continue
+				}
+
+				for !queue.TryPush(value) {
+					time.Sleep(time.Microsecond)
+				}
+			}
+		}()
+	}
+
+	var sum atomic.Int64
+	var count atomic.Int64
+	seen := make([]atomic.Bool, producers*perProducer)
+
+	var consumed sync.WaitGroup
+	for range consumers {
+		consumed.Add(1)
+
+		go func() {
+			defer consumed.Done()
+
+			for {
+				value, err := queue.PopCtx(ctx)
+				if err != nil {
+					assert.ErrorIs(t, err, ErrQueueClosed)
+					return
+				}
+
+				assert.False(t, seen[value].Swap(true), "value %d popped twice", value)
+				sum.Add(int64(value))
+				count.Add(1)
+			}
+		}()
+	}
+
+	produced.Wait()
+	queue.Close()
+	consumed.Wait()
+
+	total := producers * perProducer
+	assert.Equal(t, int64(total), count.Load())
+	assert.Equal(t, int64(total*(total-1)/2), sum.Load())
+	assert.Zero(t, queue.Len())
+}
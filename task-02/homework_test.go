@@ -1,31 +1,72 @@
 package main
 
 import (
-	"golang.org/x/exp/constraints"
+	"iter"
+	"slices"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 )
 
 // go test -v homework_test.go
 
+// FullPolicy decides what Push does when the queue is full.
+type FullPolicy int
+
+const (
+	// RejectWhenFull makes Push return false.
+	RejectWhenFull FullPolicy = iota
+	// OverwriteWhenFull makes Push evict the oldest element.
+	OverwriteWhenFull
+	// GrowWhenFull makes Push double the capacity.
+	GrowWhenFull
+)
+
+type queueConfig struct {
+	policy FullPolicy
+}
+
+type QueueOption func(*queueConfig)
+
+func WithFullPolicy(policy FullPolicy) QueueOption {
+	return func(c *queueConfig) {
+		c.policy = policy
+	}
+}
+
 // CircularQueue is not thread-safe.
-type CircularQueue[T constraints.Signed] struct {
+type CircularQueue[T any] struct {
 	values []T
 	head   int
 	tail   int
 	count  int
+	policy FullPolicy
 }
 
-func NewCircularQueue[T constraints.Signed](size int) CircularQueue[T] {
+func NewCircularQueue[T any](size int, opts ...QueueOption) CircularQueue[T] {
+	var config queueConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return CircularQueue[T]{
 		values: make([]T, size),
+		policy: config.policy,
 	}
 }
 
 func (q *CircularQueue[T]) Push(value T) bool {
 	if q.Full() {
-		return false
+		switch q.policy {
+		case OverwriteWhenFull:
+			q.PushEvict(value)
+			return true
+		case GrowWhenFull:
+			q.grow()
+		default:
+			return false
+		}
 	}
 
 	q.values[q.tail] = value
@@ -35,31 +76,220 @@ func (q *CircularQueue[T]) Push(value T) bool {
 	return true
 }
 
-func (q *CircularQueue[T]) Pop() bool {
+// PushEvict pushes value regardless of the policy, evicting and returning
+// the oldest element if the queue is full. A zero-capacity queue evicts value itself.
+func (q *CircularQueue[T]) PushEvict(value T) (T, bool) {
+	if len(q.values) == 0 {
+		return value, true
+	}
+
+	if !q.Full() {
+		q.values[q.tail] = value
+		q.tail = (q.tail + 1) % len(q.values)
+		q.count++
+
+		var zero T
+		return zero, false
+	}
+
+	evicted := q.values[q.head]
+	q.values[q.head] = value
+	q.head = (q.head + 1) % len(q.values)
+	q.tail = q.head
+
+	return evicted, true
+}
+
+func (q *CircularQueue[T]) Pop() (T, bool) {
 	if q.Empty() {
-		return false
+		var zero T
+		return zero, false
 	}
 
+	value := q.values[q.head]
+
+	var zero T
+	q.values[q.head] = zero
 	q.head = (q.head + 1) % len(q.values)
 	q.count--
 
+	return value, true
+}
+
+func (q *CircularQueue[T]) Front() (T, bool) {
+	if q.Empty() {
+		var zero T
+		return zero, false
+	}
+
+	return q.values[q.head], true
+}
+
+func (q *CircularQueue[T]) Back() (T, bool) {
+	if q.Empty() {
+		var zero T
+		return zero, false
+	}
+
+	return q.values[(q.tail-1+len(q.values))%len(q.values)], true
+}
+
+// PushFront follows the same policy as Push, but an overwriting queue evicts the newest element.
+func (q *CircularQueue[T]) PushFront(value T) bool {
+	if q.Full() {
+		switch q.policy {
+		case OverwriteWhenFull:
+			if len(q.values) == 0 {
+				return true
+			}
+
+			q.PopBack()
+		case GrowWhenFull:
+			q.grow()
+		default:
+			return false
+		}
+	}
+
+	q.head = (q.head - 1 + len(q.values)) % len(q.values)
+	q.values[q.head] = value
+	q.count++
+
 	return true
 }
 
-func (q *CircularQueue[T]) Front() T {
+func (q *CircularQueue[T]) PopBack() (T, bool) {
 	if q.Empty() {
-		return q.notFoundValue()
+		var zero T
+		return zero, false
+	}
+
+	q.tail = (q.tail - 1 + len(q.values)) % len(q.values)
+	value := q.values[q.tail]
+
+	var zero T
+	q.values[q.tail] = zero
+	q.count--
+
+	return value, true
+}
+
+// At returns the i-th element counting from the front.
+func (q *CircularQueue[T]) At(i int) (T, bool) {
+	if i < 0 || i >= q.count {
+		var zero T
+		return zero, false
 	}
 
-	return q.values[q.head]
+	return q.values[(q.head+i)%len(q.values)], true
 }
 
-func (q *CircularQueue[T]) Back() T {
+// All iterates over the elements from front to back.
+func (q *CircularQueue[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < q.count; i++ {
+			if !yield(i, q.values[(q.head+i)%len(q.values)]) {
+				return
+			}
+		}
+	}
+}
+
+// Drain appends all elements to dst from front to back and empties the queue.
+func (q *CircularQueue[T]) Drain(dst []T) []T {
+	n := len(dst)
+	dst = slices.Grow(dst, q.count)[:n+q.count]
+
+	q.PopInto(dst[n:])
+
+	return dst
+}
+
+// PushSlice pushes values in order following the policy and returns how many were accepted.
+// An overwriting queue accepts all of them and keeps only the newest Cap elements.
+func (q *CircularQueue[T]) PushSlice(values []T) int {
+	accepted := len(values)
+
+	switch q.policy {
+	case GrowWhenFull:
+		for q.count+len(values) > len(q.values) {
+			q.grow()
+		}
+	case OverwriteWhenFull:
+		if len(values) >= len(q.values) {
+			q.Clear()
+			values = values[len(values)-len(q.values):]
+		}
+
+		for q.count+len(values) > len(q.values) {
+			q.Pop()
+		}
+	default:
+		values = values[:min(len(values), len(q.values)-q.count)]
+		accepted = len(values)
+	}
+
+	if len(values) == 0 {
+		return accepted
+	}
+
+	copied := copy(q.values[q.tail:], values)
+	copy(q.values, values[copied:])
+
+	q.tail = (q.tail + len(values)) % len(q.values)
+	q.count += len(values)
+
+	return accepted
+}
+
+// PopInto pops up to len(dst) elements into dst and returns how many were popped.
+func (q *CircularQueue[T]) PopInto(dst []T) int {
+	n := min(len(dst), q.count)
+	if n == 0 {
+		return 0
+	}
+
+	first, second := q.Peek()
+	first = first[:min(n, len(first))]
+	second = second[:n-len(first)]
+
+	copy(dst, first)
+	copy(dst[len(first):], second)
+
+	clear(first)
+	clear(second)
+
+	q.head = (q.head + n) % len(q.values)
+	q.count -= n
+
+	return n
+}
+
+// Peek returns the elements from front to back as two contiguous ranges
+// of the underlying storage. They are valid until the next modification.
+func (q *CircularQueue[T]) Peek() (first, second []T) {
 	if q.Empty() {
-		return q.notFoundValue()
+		return nil, nil
 	}
 
-	return q.values[(q.tail-1+len(q.values))%len(q.values)]
+	end := min(q.head+q.count, len(q.values))
+
+	return q.values[q.head:end], q.values[:q.count-(end-q.head)]
+}
+
+func (q *CircularQueue[T]) Clear() {
+	clear(q.values)
+	q.head = 0
+	q.tail = 0
+	q.count = 0
+}
+
+func (q *CircularQueue[T]) Len() int {
+	return q.count
+}
+
+func (q *CircularQueue[T]) Cap() int {
+	return len(q.values)
 }
 
 func (q *CircularQueue[T]) Empty() bool {
@@ -70,10 +300,20 @@ func (q *CircularQueue[T]) Full() bool {
 	return q.count >= len(q.values)
 }
 
-func (q *CircularQueue[T]) notFoundValue() T {
-	var zero T
+// grow doubles the capacity and re-linearizes values so that head is at index 0.
+func (q *CircularQueue[T]) grow() {
+	values := make([]T, max(1, 2*len(q.values)))
 
-	return zero - 1
+	if q.count > 0 {
+		n := copy(values, q.values[q.head:])
+		if n < q.count {
+			copy(values[n:], q.values[:q.tail])
+		}
+	}
+
+	q.values = values
+	q.head = 0
+	q.tail = q.count
 }
 
 func TestCircularQueueInt(t *testing.T) {
@@ -83,9 +323,9 @@ func TestCircularQueueInt(t *testing.T) {
 	assert.True(t, queue.Empty())
 	assert.False(t, queue.Full())
 
-	assert.Equal(t, -1, queue.Front())
-	assert.Equal(t, -1, queue.Back())
-	assert.False(t, queue.Pop())
+	assertNotFound[int](t)(queue.Front())
+	assertNotFound[int](t)(queue.Back())
+	assertNotFound[int](t)(queue.Pop())
 
 	assert.True(t, queue.Push(1))
 	assert.True(t, queue.Push(2))
@@ -97,23 +337,23 @@ func TestCircularQueueInt(t *testing.T) {
 	assert.False(t, queue.Empty())
 	assert.True(t, queue.Full())
 
-	assert.Equal(t, 1, queue.Front())
-	assert.Equal(t, 3, queue.Back())
+	assertFound(t, 1)(queue.Front())
+	assertFound(t, 3)(queue.Back())
 
-	assert.True(t, queue.Pop())
+	assertFound(t, 1)(queue.Pop())
 	assert.False(t, queue.Empty())
 	assert.False(t, queue.Full())
 	assert.True(t, queue.Push(4))
 
 	assert.Equal(t, []int{4, 2, 3}, queue.values)
 
-	assert.Equal(t, 2, queue.Front())
-	assert.Equal(t, 4, queue.Back())
+	assertFound(t, 2)(queue.Front())
+	assertFound(t, 4)(queue.Back())
 
-	assert.True(t, queue.Pop())
-	assert.True(t, queue.Pop())
-	assert.True(t, queue.Pop())
-	assert.False(t, queue.Pop())
+	assertFound(t, 2)(queue.Pop())
+	assertFound(t, 3)(queue.Pop())
+	assertFound(t, 4)(queue.Pop())
+	assertNotFound[int](t)(queue.Pop())
 
 	assert.True(t, queue.Empty())
 	assert.False(t, queue.Full())
@@ -126,22 +366,22 @@ func TestCircularQueueInt8(t *testing.T) {
 	assert.True(t, queue.Empty())
 	assert.False(t, queue.Full())
 
-	assert.Equal(t, int8(-1), queue.Front())
-	assert.Equal(t, int8(-1), queue.Back())
-	assert.False(t, queue.Pop())
+	assertNotFound[int8](t)(queue.Front())
+	assertNotFound[int8](t)(queue.Back())
+	assertNotFound[int8](t)(queue.Pop())
 
 	assert.True(t, queue.Push(10))
 	assert.True(t, queue.Push(20))
 	assert.False(t, queue.Push(30))
 
-	assert.Equal(t, int8(10), queue.Front())
-	assert.Equal(t, int8(20), queue.Back())
+	assertFound(t, int8(10))(queue.Front())
+	assertFound(t, int8(20))(queue.Back())
 
-	assert.True(t, queue.Pop())
+	assertFound(t, int8(10))(queue.Pop())
 	assert.True(t, queue.Push(30))
 
-	assert.Equal(t, int8(20), queue.Front())
-	assert.Equal(t, int8(30), queue.Back())
+	assertFound(t, int8(20))(queue.Front())
+	assertFound(t, int8(30))(queue.Back())
 }
 
 func TestCircularQueueInt64(t *testing.T) {
@@ -151,22 +391,22 @@ func TestCircularQueueInt64(t *testing.T) {
 	assert.True(t, queue.Empty())
 	assert.False(t, queue.Full())
 
-	assert.Equal(t, int64(-1), queue.Front())
-	assert.Equal(t, int64(-1), queue.Back())
-	assert.False(t, queue.Pop())
+	assertNotFound[int64](t)(queue.Front())
+	assertNotFound[int64](t)(queue.Back())
+	assertNotFound[int64](t)(queue.Pop())
 
 	assert.True(t, queue.Push(1<<40))
 	assert.True(t, queue.Push(1<<41))
 	assert.False(t, queue.Push(1<<42))
 
-	assert.Equal(t, int64(1<<40), queue.Front())
-	assert.Equal(t, int64(1<<41), queue.Back())
+	assertFound(t, int64(1<<40))(queue.Front())
+	assertFound(t, int64(1<<41))(queue.Back())
 
-	assert.True(t, queue.Pop())
+	assertFound(t, int64(1<<40))(queue.Pop())
 	assert.True(t, queue.Push(1<<42))
 
-	assert.Equal(t, int64(1<<41), queue.Front())
-	assert.Equal(t, int64(1<<42), queue.Back())
+	assertFound(t, int64(1<<41))(queue.Front())
+	assertFound(t, int64(1<<42))(queue.Back())
 }
 
 func TestCircularQueueOneSizeCycle(t *testing.T) {
@@ -175,15 +415,458 @@ func TestCircularQueueOneSizeCycle(t *testing.T) {
 
 	for i := 0; i < 5; i++ {
 		assert.True(t, queue.Push(i))
-		assert.Equal(t, i, queue.Front())
-		assert.Equal(t, i, queue.Back())
+		assertFound(t, i)(queue.Front())
+		assertFound(t, i)(queue.Back())
 		assert.True(t, queue.Full())
 		assert.False(t, queue.Empty())
-		assert.True(t, queue.Pop())
-		assert.Equal(t, -1, queue.Front())
-		assert.Equal(t, -1, queue.Back())
+		assertFound(t, i)(queue.Pop())
+		assertNotFound[int](t)(queue.Front())
+		assertNotFound[int](t)(queue.Back())
 		assert.False(t, queue.Full())
-		assert.False(t, queue.Pop())
+		assertNotFound[int](t)(queue.Pop())
 		assert.True(t, queue.Empty())
 	}
 }
+
+func TestCircularQueueString(t *testing.T) {
+	queue := NewCircularQueue[string](2)
+
+	assertNotFound[string](t)(queue.Front())
+	assertNotFound[string](t)(queue.Pop())
+
+	assert.True(t, queue.Push("-1"))
+	assert.True(t, queue.Push(""))
+	assert.False(t, queue.Push("c"))
+
+	assertFound(t, "-1")(queue.Front())
+	assertFound(t, "")(queue.Back())
+
+	assertFound(t, "-1")(queue.Pop())
+	assertFound(t, "")(queue.Pop())
+	assertNotFound[string](t)(queue.Pop())
+}
+
+func TestCircularQueueSentinelValue(t *testing.T) {
+	queue := NewCircularQueue[int](1)
+
+	assert.True(t, queue.Push(-1))
+	assertFound(t, -1)(queue.Front())
+	assertFound(t, -1)(queue.Back())
+	assertFound(t, -1)(queue.Pop())
+	assertNotFound[int](t)(queue.Front())
+}
+
+func TestCircularQueueStruct(t *testing.T) {
+	type point struct {
+		x, y uint
+	}
+
+	queue := NewCircularQueue[point](2)
+
+	assert.True(t, queue.Push(point{x: 1, y: 2}))
+	assert.True(t, queue.Push(point{}))
+
+	assertFound(t, point{x: 1, y: 2})(queue.Pop())
+	assertFound(t, point{})(queue.Front())
+	assertFound(t, point{})(queue.Back())
+}
+
+func TestCircularQueuePopReleasesValue(t *testing.T) {
+	queue := NewCircularQueue[*int](1)
+	value := new(int)
+
+	assert.True(t, queue.Push(value))
+	assertFound(t, value)(queue.Pop())
+	assert.Nil(t, queue.values[0])
+}
+
+func assertFound[T any](t *testing.T, expected T) func(T, bool) {
+	return func(value T, ok bool) {
+		t.Helper()
+
+		assert.True(t, ok)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func assertNotFound[T any](t *testing.T) func(T, bool) {
+	return func(value T, ok bool) {
+		t.Helper()
+
+		var zero T
+		assert.False(t, ok)
+		assert.Equal(t, zero, value)
+	}
+}
+
+func TestCircularQueueOverwrite(t *testing.T) {
+	queue := NewCircularQueue[int](3, WithFullPolicy(OverwriteWhenFull))
+
+	for i := 1; i <= 3; i++ {
+		assert.True(t, queue.Push(i))
+	}
+
+	assert.True(t, queue.Full())
+	assert.True(t, queue.Push(4))
+	assert.Equal(t, []int{4, 2, 3}, queue.values)
+
+	assertFound(t, 2)(queue.Front())
+	assertFound(t, 4)(queue.Back())
+
+	assertFound(t, 2)(queue.PushEvict(5))
+	assertFound(t, 3)(queue.PushEvict(6))
+	assertFound(t, 4)(queue.PushEvict(7))
+	assert.Equal(t, []int{7, 5, 6}, queue.values)
+
+	assertFound(t, 5)(queue.Pop())
+	assertNotFound[int](t)(queue.PushEvict(8))
+
+	assertFound(t, 6)(queue.Pop())
+	assertFound(t, 7)(queue.Pop())
+	assertFound(t, 8)(queue.Pop())
+	assertNotFound[int](t)(queue.Pop())
+}
+
+func TestCircularQueuePushEvictIgnoresPolicy(t *testing.T) {
+	queue := NewCircularQueue[string](1)
+
+	assert.True(t, queue.Push("a"))
+	assert.False(t, queue.Push("b"))
+	assertFound(t, "a")(queue.PushEvict("b"))
+	assertFound(t, "b")(queue.Front())
+}
+
+func TestCircularQueueOverwriteZeroSize(t *testing.T) {
+	queue := NewCircularQueue[int](0, WithFullPolicy(OverwriteWhenFull))
+
+	assert.True(t, queue.Push(1))
+	assert.True(t, queue.Empty())
+	assertFound(t, 2)(queue.PushEvict(2))
+	assert.True(t, queue.Empty())
+}
+
+func TestCircularQueueGrow(t *testing.T) {
+	queue := NewCircularQueue[int](3, WithFullPolicy(GrowWhenFull))
+
+	assert.True(t, queue.Push(1))
+	assert.True(t, queue.Push(2))
+	assert.True(t, queue.Push(3))
+	assertFound(t, 1)(queue.Pop())
+	assert.True(t, queue.Push(4))
+	assert.Equal(t, []int{4, 2, 3}, queue.values)
+
+	assert.True(t, queue.Push(5))
+	assert.Equal(t, []int{2, 3, 4, 5, 0, 0}, queue.values)
+	assert.False(t, queue.Full())
+
+	assertFound(t, 2)(queue.Front())
+	assertFound(t, 5)(queue.Back())
+
+	assert.True(t, queue.Push(6))
+	assert.True(t, queue.Push(7))
+	assert.True(t, queue.Full())
+	assert.True(t, queue.Push(8))
+	assert.Len(t, queue.values, 12)
+
+	for i := 2; i <= 8; i++ {
+		assertFound(t, i)(queue.Pop())
+	}
+
+	assertNotFound[int](t)(queue.Pop())
+}
+
+func TestCircularQueueGrowWrapped(t *testing.T) {
+	queue := NewCircularQueue[int](4, WithFullPolicy(GrowWhenFull))
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 4; i++ {
+			assert.True(t, queue.Push(round*10+i))
+		}
+
+		for i := 0; i < 3; i++ {
+			assertFound(t, round*10+i)(queue.Pop())
+		}
+
+		assertFound(t, round*10+3)(queue.Pop())
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, queue.Push(i))
+	}
+
+	assertFound(t, 0)(queue.Pop())
+	assertFound(t, 1)(queue.Pop())
+
+	for i := 3; i < 9; i++ {
+		assert.True(t, queue.Push(i))
+	}
+
+	assert.Len(t, queue.values, 8)
+	assert.Equal(t, 0, queue.head)
+
+	for i := 2; i < 9; i++ {
+		assertFound(t, i)(queue.Pop())
+	}
+}
+
+func TestCircularQueueGrowFromZero(t *testing.T) {
+	queue := NewCircularQueue[int](0, WithFullPolicy(GrowWhenFull))
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, queue.Push(i))
+	}
+
+	assert.Len(t, queue.values, 8)
+
+	for i := 0; i < 5; i++ {
+		assertFound(t, i)(queue.Pop())
+	}
+}
+
+func TestCircularQueueDeque(t *testing.T) {
+	queue := NewCircularQueue[int](4)
+
+	assertNotFound[int](t)(queue.PopBack())
+
+	assert.True(t, queue.PushFront(2))
+	assert.True(t, queue.PushFront(1))
+	assert.True(t, queue.Push(3))
+	assert.True(t, queue.Push(4))
+	assert.False(t, queue.PushFront(0))
+
+	assert.Equal(t, []int{3, 4, 1, 2}, queue.values)
+	assert.Equal(t, 4, queue.Len())
+	assert.Equal(t, 4, queue.Cap())
+
+	assertFound(t, 1)(queue.Front())
+	assertFound(t, 4)(queue.Back())
+
+	assertFound(t, 4)(queue.PopBack())
+	assertFound(t, 3)(queue.PopBack())
+	assertFound(t, 1)(queue.Pop())
+	assertFound(t, 2)(queue.PopBack())
+	assertNotFound[int](t)(queue.PopBack())
+	assert.True(t, queue.Empty())
+}
+
+func TestCircularQueuePushFrontPolicies(t *testing.T) {
+	overwrite := NewCircularQueue[int](2, WithFullPolicy(OverwriteWhenFull))
+	assert.True(t, overwrite.Push(1))
+	assert.True(t, overwrite.Push(2))
+	assert.True(t, overwrite.PushFront(0))
+	assert.Equal(t, []int{0, 1}, overwrite.Drain(nil))
+
+	grow := NewCircularQueue[int](2, WithFullPolicy(GrowWhenFull))
+	assert.True(t, grow.Push(1))
+	assert.True(t, grow.Push(2))
+	assert.True(t, grow.PushFront(0))
+	assert.Equal(t, 4, grow.Cap())
+	assert.Equal(t, []int{0, 1, 2}, grow.Drain(nil))
+
+	zero := NewCircularQueue[int](0, WithFullPolicy(OverwriteWhenFull))
+	assert.True(t, zero.PushFront(1))
+	assert.True(t, zero.Empty())
+}
+
+func TestCircularQueueAt(t *testing.T) {
+	queue := NewCircularQueue[string](3)
+
+	assertNotFound[string](t)(queue.At(0))
+
+	assert.True(t, queue.Push("a"))
+	assert.True(t, queue.Push("b"))
+	assert.True(t, queue.Push("c"))
+	assertFound(t, "a")(queue.Pop())
+	assert.True(t, queue.Push("d"))
+
+	assertFound(t, "b")(queue.At(0))
+	assertFound(t, "c")(queue.At(1))
+	assertFound(t, "d")(queue.At(2))
+	assertNotFound[string](t)(queue.At(3))
+	assertNotFound[string](t)(queue.At(-1))
+}
+
+func TestCircularQueueAll(t *testing.T) {
+	queue := NewCircularQueue[int](3)
+
+	for range queue.All() {
+		t.Fatal("empty queue must not yield")
+	}
+
+	assert.True(t, queue.Push(1))
+	assert.True(t, queue.Push(2))
+	assert.True(t, queue.Push(3))
+	assertFound(t, 1)(queue.Pop())
+	assert.True(t, queue.Push(4))
+
+	var indexes, values []int
+	for i, v := range queue.All() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, indexes)
+	assert.Equal(t, []int{2, 3, 4}, values)
+
+	values = nil
+	for _, v := range queue.All() {
+		values = append(values, v)
+		break
+	}
+
+	assert.Equal(t, []int{2}, values)
+}
+
+func TestCircularQueueDrain(t *testing.T) {
+	queue := NewCircularQueue[int](4)
+
+	assert.Nil(t, queue.Drain(nil))
+
+	for i := 1; i <= 4; i++ {
+		assert.True(t, queue.Push(i))
+	}
+
+	assertFound(t, 1)(queue.Pop())
+	assertFound(t, 2)(queue.Pop())
+	assert.True(t, queue.Push(5))
+
+	assert.Equal(t, []int{0, 3, 4, 5}, queue.Drain([]int{0}))
+	assert.True(t, queue.Empty())
+	assert.Equal(t, []int{0, 0, 0, 0}, queue.values)
+
+	assert.True(t, queue.Push(6))
+	assert.True(t, queue.Push(7))
+	assert.Equal(t, []int{6, 7}, queue.Drain(make([]int, 0, 1)))
+}
+
+func TestCircularQueueClear(t *testing.T) {
+	queue := NewCircularQueue[*int](2)
+
+	assert.True(t, queue.Push(new(int)))
+	assert.True(t, queue.Push(new(int)))
+	queue.Clear()
+
+	assert.True(t, queue.Empty())
+	assert.Zero(t, queue.Len())
+	assert.Equal(t, 2, queue.Cap())
+	assert.Equal(t, []*int{nil, nil}, queue.values)
+	assertNotFound[*int](t)(queue.Front())
+
+	assert.True(t, queue.Push(nil))
+	assert.Equal(t, 1, queue.Len())
+}
+
+func TestCircularQueuePushSlice(t *testing.T) {
+	queue := NewCircularQueue[int](4)
+
+	assert.Equal(t, 0, queue.PushSlice(nil))
+	assert.Equal(t, 3, queue.PushSlice([]int{1, 2, 3}))
+	assertFound(t, 1)(queue.Pop())
+	assertFound(t, 2)(queue.Pop())
+
+	assert.Equal(t, 3, queue.PushSlice([]int{4, 5, 6, 7}))
+	assert.Equal(t, []int{5, 6, 3, 4}, queue.values)
+	assert.True(t, queue.Full())
+	assert.Equal(t, 0, queue.PushSlice([]int{8}))
+
+	assert.Equal(t, []int{3, 4, 5, 6}, queue.Drain(nil))
+}
+
+func TestCircularQueuePushSlicePolicies(t *testing.T) {
+	overwrite := NewCircularQueue[int](3, WithFullPolicy(OverwriteWhenFull))
+	assert.Equal(t, 2, overwrite.PushSlice([]int{1, 2}))
+	assert.Equal(t, 2, overwrite.PushSlice([]int{3, 4}))
+	assert.Equal(t, []int{2, 3, 4}, overwrite.Drain(nil))
+
+	assert.Equal(t, 2, overwrite.PushSlice([]int{1, 2}))
+	assert.Equal(t, 5, overwrite.PushSlice([]int{3, 4, 5, 6, 7}))
+	assert.Equal(t, []int{5, 6, 7}, overwrite.Drain(nil))
+
+	grow := NewCircularQueue[int](2, WithFullPolicy(GrowWhenFull))
+	assert.True(t, grow.Push(0))
+	assertFound(t, 0)(grow.Pop())
+	assert.True(t, grow.Push(1))
+	assert.Equal(t, 5, grow.PushSlice([]int{2, 3, 4, 5, 6}))
+	assert.Equal(t, 8, grow.Cap())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, grow.Drain(nil))
+
+	zero := NewCircularQueue[int](0)
+	assert.Equal(t, 0, zero.PushSlice([]int{1}))
+
+	zeroOverwrite := NewCircularQueue[int](0, WithFullPolicy(OverwriteWhenFull))
+	assert.Equal(t, 1, zeroOverwrite.PushSlice([]int{1}))
+	assert.True(t, zeroOverwrite.Empty())
+}
+
+func TestCircularQueuePopInto(t *testing.T) {
+	queue := NewCircularQueue[int](4)
+	dst := make([]int, 3)
+
+	assert.Equal(t, 0, queue.PopInto(dst))
+
+	assert.Equal(t, 4, queue.PushSlice([]int{1, 2, 3, 4}))
+	assertFound(t, 1)(queue.Pop())
+	assertFound(t, 2)(queue.Pop())
+	assert.Equal(t, 2, queue.PushSlice([]int{5, 6}))
+
+	assert.Equal(t, 3, queue.PopInto(dst))
+	assert.Equal(t, []int{3, 4, 5}, dst)
+	assert.Equal(t, []int{0, 6, 0, 0}, queue.values)
+
+	assert.Equal(t, 1, queue.PopInto(dst))
+	assert.Equal(t, []int{6, 4, 5}, dst)
+	assert.True(t, queue.Empty())
+
+	assert.Equal(t, 0, queue.PopInto(nil))
+}
+
+func TestCircularQueuePeek(t *testing.T) {
+	queue := NewCircularQueue[int](4)
+
+	first, second := queue.Peek()
+	assert.Nil(t, first)
+	assert.Nil(t, second)
+
+	assert.Equal(t, 3, queue.PushSlice([]int{1, 2, 3}))
+	first, second = queue.Peek()
+	assert.Equal(t, []int{1, 2, 3}, first)
+	assert.Empty(t, second)
+
+	assertFound(t, 1)(queue.Pop())
+	assert.Equal(t, 2, queue.PushSlice([]int{4, 5}))
+	first, second = queue.Peek()
+	assert.Equal(t, []int{2, 3, 4}, first)
+	assert.Equal(t, []int{5}, second)
+
+	assert.Equal(t, unsafe.SliceData(queue.values[1:]), unsafe.SliceData(first))
+	assert.Equal(t, unsafe.SliceData(queue.values), unsafe.SliceData(second))
+}
+
+const benchmarkBatchSize = 64
+
+func BenchmarkCircularQueueSingle(b *testing.B) {
+	queue := NewCircularQueue[int](4 * benchmarkBatchSize)
+	batch := make([]int, benchmarkBatchSize)
+	assert.Equal(b, 3*benchmarkBatchSize/2, queue.PushSlice(make([]int, 3*benchmarkBatchSize/2)))
+
+	for b.Loop() {
+		for _, v := range batch {
+			queue.Push(v)
+		}
+
+		for i := range batch {
+			batch[i], _ = queue.Pop()
+		}
+	}
+}
+
+func BenchmarkCircularQueueBatch(b *testing.B) {
+	queue := NewCircularQueue[int](4 * benchmarkBatchSize)
+	batch := make([]int, benchmarkBatchSize)
+	assert.Equal(b, 3*benchmarkBatchSize/2, queue.PushSlice(make([]int, 3*benchmarkBatchSize/2)))
+
+	for b.Loop() {
+		queue.PushSlice(batch)
+		queue.PopInto(batch)
+	}
+}
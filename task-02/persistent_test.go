@@ -0,0 +1,486 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// go test -v homework_test.go persistent_test.go
+
+var (
+	ErrRecordSize     = errors.New("record size mismatch")
+	ErrLayoutMismatch = errors.New("queue layout mismatch")
+	ErrCorrupted      = errors.New("queue file corrupted")
+)
+
+const (
+	persistentMagic = 0x31305143 // "CQ01"
+	headerSlotSize  = 64
+	headerSlots     = 2
+	headerDataSize  = 40
+	recordsOffset   = headerSlots * headerSlotSize
+	persistentPerm  = 0o644
+	persistentProt  = syscall.PROT_READ | syscall.PROT_WRITE
+)
+
+type persistentHeader struct {
+	recordSize uint32
+	capacity   uint32
+	seq        uint64
+	head       uint64
+	count      uint64
+}
+
+// PersistentQueue is a CircularQueue of fixed-size records stored in a memory-mapped file.
+//
+// The file starts with two header slots holding head, count and a CRC32 checksum.
+// Every update writes and syncs the records first and then commits a new header
+// into the inactive slot, so a torn write leaves the previous header intact and
+// the queue recovers to the last committed state on open. Writes to the mapping
+// are flushed with msync, since POSIX doesn't require fsync to flush them.
+// PersistentQueue is not thread-safe.
+type PersistentQueue struct {
+	file   *os.File
+	data   []byte
+	header persistentHeader
+	active int
+}
+
+func OpenPersistentQueue(path string, recordSize, capacity int) (*PersistentQueue, error) {
+	if recordSize <= 0 || capacity <= 0 {
+		return nil, fmt.Errorf("%w: record size %d, capacity %d", ErrLayoutMismatch, recordSize, capacity)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, persistentPerm)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := openPersistentQueue(file, uint32(recordSize), uint32(capacity))
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func openPersistentQueue(file *os.File, recordSize, capacity uint32) (*PersistentQueue, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(recordsOffset) + int64(recordSize)*int64(capacity)
+	if info.Size() == 0 {
+		if err := file.Truncate(size); err != nil {
+			return nil, err
+		}
+	} else if info.Size() != size {
+		return nil, fmt.Errorf("%w: file size %d, expected %d", ErrLayoutMismatch, info.Size(), size)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), persistentProt, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &PersistentQueue{file: file, data: data}
+	if err := q.recover(recordSize, capacity); err != nil {
+		_ = syscall.Munmap(data)
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// recover picks the valid header slot with the highest sequence number.
+// A file whose header slots were never written is initialized as empty.
+func (q *PersistentQueue) recover(recordSize, capacity uint32) error {
+	found := false
+
+	for slot := range headerSlots {
+		header, ok := decodeHeader(q.headerSlot(slot))
+		if ok && (!found || header.seq > q.header.seq) {
+			q.header = header
+			q.active = slot
+			found = true
+		}
+	}
+
+	if !found {
+		if !isZero(q.data[:recordsOffset]) {
+			return ErrCorrupted
+		}
+
+		q.header = persistentHeader{recordSize: recordSize, capacity: capacity}
+		q.active = headerSlots - 1
+
+		return q.commit(0, 0)
+	}
+
+	if q.header.recordSize != recordSize || q.header.capacity != capacity {
+		return fmt.Errorf("%w: stored record size %d, capacity %d",
+			ErrLayoutMismatch, q.header.recordSize, q.header.capacity)
+	}
+
+	if q.header.head >= uint64(capacity) || q.header.count > uint64(capacity) {
+		return fmt.Errorf("%w: head %d, count %d", ErrCorrupted, q.header.head, q.header.count)
+	}
+
+	return nil
+}
+
+func (q *PersistentQueue) Push(record []byte) (bool, error) {
+	if q.data == nil {
+		return false, os.ErrClosed
+	}
+
+	if len(record) != int(q.header.recordSize) {
+		return false, fmt.Errorf("%w: got %d bytes, expected %d", ErrRecordSize, len(record), q.header.recordSize)
+	}
+
+	if q.Full() {
+		return false, nil
+	}
+
+	index := (q.header.head + q.header.count) % uint64(q.header.capacity)
+	copy(q.record(index), record)
+
+	offset := q.recordOffset(index)
+	if err := q.msync(offset, offset+int(q.header.recordSize)); err != nil {
+		return false, err
+	}
+
+	if err := q.commit(q.header.head, q.header.count+1); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (q *PersistentQueue) Pop() ([]byte, bool, error) {
+	if q.data == nil {
+		return nil, false, os.ErrClosed
+	}
+
+	record, ok := q.Front()
+	if !ok {
+		return nil, false, nil
+	}
+
+	if err := q.commit((q.header.head+1)%uint64(q.header.capacity), q.header.count-1); err != nil {
+		return nil, false, err
+	}
+
+	return record, true, nil
+}
+
+// Front returns a copy of the oldest record.
+func (q *PersistentQueue) Front() ([]byte, bool) {
+	if q.data == nil || q.Empty() {
+		return nil, false
+	}
+
+	return bytes.Clone(q.record(q.header.head)), true
+}
+
+func (q *PersistentQueue) Len() int {
+	return int(q.header.count)
+}
+
+func (q *PersistentQueue) Cap() int {
+	return int(q.header.capacity)
+}
+
+func (q *PersistentQueue) Empty() bool {
+	return q.header.count == 0
+}
+
+func (q *PersistentQueue) Full() bool {
+	return q.header.count >= uint64(q.header.capacity)
+}
+
+func (q *PersistentQueue) Close() error {
+	if q.data == nil {
+		return os.ErrClosed
+	}
+
+	err := syscall.Munmap(q.data)
+	q.data = nil
+	// A closed queue keeps its layout but no longer holds any records.
+	q.header = persistentHeader{recordSize: q.header.recordSize, capacity: q.header.capacity}
+
+	return errors.Join(err, q.file.Close())
+}
+
+func (q *PersistentQueue) commit(head, count uint64) error {
+	next := q.header
+	next.seq++
+	next.head = head
+	next.count = count
+
+	slot := (q.active + 1) % headerSlots
+	encodeHeader(q.headerSlot(slot), next)
+
+	if err := q.msync(slot*headerSlotSize, (slot+1)*headerSlotSize); err != nil {
+		return err
+	}
+
+	q.header = next
+	q.active = slot
+
+	return nil
+}
+
+func (q *PersistentQueue) headerSlot(slot int) []byte {
+	return q.data[slot*headerSlotSize : (slot+1)*headerSlotSize]
+}
+
+func (q *PersistentQueue) record(index uint64) []byte {
+	offset := q.recordOffset(index)
+	return q.data[offset : offset+int(q.header.recordSize)]
+}
+
+func (q *PersistentQueue) recordOffset(index uint64) int {
+	return recordsOffset + int(index)*int(q.header.recordSize)
+}
+
+// msync synchronously writes the mapped bytes in [from, to) to the file.
+func (q *PersistentQueue) msync(from, to int) error {
+	from -= from % os.Getpagesize()
+
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC,
+		uintptr(unsafe.Pointer(&q.data[from])), uintptr(to-from), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func encodeHeader(buf []byte, h persistentHeader) {
+	clear(buf)
+
+	binary.LittleEndian.PutUint32(buf[0:], persistentMagic)
+	binary.LittleEndian.PutUint32(buf[4:], h.recordSize)
+	binary.LittleEndian.PutUint32(buf[8:], h.capacity)
+	binary.LittleEndian.PutUint64(buf[16:], h.seq)
+	binary.LittleEndian.PutUint64(buf[24:], h.head)
+	binary.LittleEndian.PutUint64(buf[32:], h.count)
+	binary.LittleEndian.PutUint32(buf[headerDataSize:], crc32.ChecksumIEEE(buf[:headerDataSize]))
+}
+
+func decodeHeader(buf []byte) (persistentHeader, bool) {
+	if binary.LittleEndian.Uint32(buf[0:]) != persistentMagic {
+		return persistentHeader{}, false
+	}
+
+	if binary.LittleEndian.Uint32(buf[headerDataSize:]) != crc32.ChecksumIEEE(buf[:headerDataSize]) {
+		return persistentHeader{}, false
+	}
+
+	return persistentHeader{
+		recordSize: binary.LittleEndian.Uint32(buf[4:]),
+		capacity:   binary.LittleEndian.Uint32(buf[8:]),
+		seq:        binary.LittleEndian.Uint64(buf[16:]),
+		head:       binary.LittleEndian.Uint64(buf[24:]),
+		count:      binary.LittleEndian.Uint64(buf[32:]),
+	}, true
+}
+
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func openTestQueue(t *testing.T, path string) *PersistentQueue {
+	t.Helper()
+
+	q, err := OpenPersistentQueue(path, 4, 3)
+	require.NoError(t, err)
+
+	return q
+}
+
+func pushRecord(t *testing.T, q *PersistentQueue, record string) bool {
+	t.Helper()
+
+	ok, err := q.Push([]byte(record))
+	require.NoError(t, err)
+
+	return ok
+}
+
+func popRecord(t *testing.T, q *PersistentQueue) (string, bool) {
+	t.Helper()
+
+	record, ok, err := q.Pop()
+	require.NoError(t, err)
+
+	return string(record), ok
+}
+
+func TestPersistentQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q := openTestQueue(t, path)
+
+	assert.True(t, q.Empty())
+	assert.Equal(t, 3, q.Cap())
+	assertNotFound[string](t)(popRecord(t, q))
+
+	assert.True(t, pushRecord(t, q, "aaaa"))
+	assert.True(t, pushRecord(t, q, "bbbb"))
+	assert.True(t, pushRecord(t, q, "cccc"))
+	assert.False(t, pushRecord(t, q, "dddd"))
+	assert.True(t, q.Full())
+
+	assertFound(t, "aaaa")(popRecord(t, q))
+	assert.True(t, pushRecord(t, q, "dddd"))
+
+	_, err := q.Push([]byte("toolong"))
+	assert.ErrorIs(t, err, ErrRecordSize)
+
+	require.NoError(t, q.Close())
+	assert.ErrorIs(t, q.Close(), os.ErrClosed)
+
+	_, err = q.Push([]byte("eeee"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+
+	assert.Equal(t, 3, q.Len())
+	assertFound(t, "bbbb")(popRecord(t, q))
+	assertFound(t, "cccc")(popRecord(t, q))
+	assertFound(t, "dddd")(popRecord(t, q))
+	assertNotFound[string](t)(popRecord(t, q))
+}
+
+func TestPersistentQueueClosed(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "queue"))
+
+	assert.True(t, pushRecord(t, q, "aaaa"))
+	assert.True(t, pushRecord(t, q, "bbbb"))
+	assert.True(t, pushRecord(t, q, "cccc"))
+	require.NoError(t, q.Close())
+
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 3, q.Cap())
+	assert.True(t, q.Empty())
+	assert.False(t, q.Full())
+
+	record, ok := q.Front()
+	assert.False(t, ok)
+	assert.Nil(t, record)
+
+	record, ok, err := q.Pop()
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.False(t, ok)
+	assert.Nil(t, record)
+
+	_, err = q.Push([]byte("dddd"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestPersistentQueueTornHeaderWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q := openTestQueue(t, path)
+
+	assert.True(t, pushRecord(t, q, "aaaa"))
+	assert.True(t, pushRecord(t, q, "bbbb"))
+
+	// Simulate a crash while committing a pop: only half of the new header reaches the file.
+	next := q.header
+	next.seq++
+	next.head++
+	next.count--
+
+	var header [headerSlotSize]byte
+	encodeHeader(header[:], next)
+	copy(q.headerSlot((q.active+1)%headerSlots), header[:headerDataSize/2])
+	require.NoError(t, q.Close())
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+
+	assert.Equal(t, 2, q.Len())
+	assertFound(t, "aaaa")(popRecord(t, q))
+	assertFound(t, "bbbb")(popRecord(t, q))
+}
+
+func TestPersistentQueueTornRecordWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q := openTestQueue(t, path)
+
+	assert.True(t, pushRecord(t, q, "aaaa"))
+
+	// Simulate a crash after the record was partially written but before the header commit.
+	copy(q.record(1), "bb")
+	require.NoError(t, q.Close())
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+
+	assert.Equal(t, 1, q.Len())
+	assertFound(t, "aaaa")(popRecord(t, q))
+	assert.True(t, pushRecord(t, q, "cccc"))
+	assertFound(t, "cccc")(popRecord(t, q))
+}
+
+func TestPersistentQueueCorruptedHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q := openTestQueue(t, path)
+
+	assert.True(t, pushRecord(t, q, "aaaa"))
+
+	q.headerSlot(0)[5] ^= 0xFF
+	q.headerSlot(1)[20] ^= 0xFF
+	require.NoError(t, q.Close())
+
+	_, err := OpenPersistentQueue(path, 4, 3)
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestPersistentQueueLayoutMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	require.NoError(t, openTestQueue(t, path).Close())
+
+	_, err := OpenPersistentQueue(path, 8, 3)
+	assert.ErrorIs(t, err, ErrLayoutMismatch)
+
+	_, err = OpenPersistentQueue(path, 2, 6)
+	assert.ErrorIs(t, err, ErrLayoutMismatch)
+
+	_, err = OpenPersistentQueue(path, 0, 3)
+	assert.ErrorIs(t, err, ErrLayoutMismatch)
+}
+
+func TestPersistentQueueInterruptedInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	require.NoError(t, os.WriteFile(path, make([]byte, recordsOffset+4*3), persistentPerm))
+
+	q := openTestQueue(t, path)
+	defer q.Close()
+
+	assert.True(t, q.Empty())
+	assert.True(t, pushRecord(t, q, "aaaa"))
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go priority_test.go
+
+// Queue is the consuming side shared by every queue in this package.
+// Empty reports whether Pop would fail, so draining with
+// for !q.Empty() { q.Pop() } always terminates.
+type Queue[T any] interface {
+	Pop() (T, bool)
+	Front() (T, bool)
+	Len() int
+	Empty() bool
+}
+
+var (
+	_ Queue[int] = (*CircularQueue[int])(nil)
+	_ Queue[int] = (*PriorityQueue[int])(nil)
+	_ Queue[int] = (*DelayQueue[int])(nil)
+)
+
+// Handle refers to an element of a PriorityQueue until it is popped or removed.
+type Handle[T any] struct {
+	value T
+	index int
+}
+
+func (h *Handle[T]) Value() T {
+	return h.value
+}
+
+// PriorityQueue is a binary heap that pops the smallest element according to less.
+// PriorityQueue is not thread-safe.
+type PriorityQueue[T any] struct {
+	items []*Handle[T]
+	less  func(a, b T) bool
+}
+
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{less: less}
+}
+
+func (q *PriorityQueue[T]) Push(value T) *Handle[T] {
+	h := &Handle[T]{value: value, index: len(q.items)}
+	q.items = append(q.items, h)
+	q.up(h.index)
+
+	return h
+}
+
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	if q.Empty() {
+		var zero T
+		return zero, false
+	}
+
+	h := q.items[0]
+	q.remove(0)
+
+	return h.value, true
+}
+
+func (q *PriorityQueue[T]) Front() (T, bool) {
+	if q.Empty() {
+		var zero T
+		return zero, false
+	}
+
+	return q.items[0].value, true
+}
+
+// Update replaces the value of h and restores the heap order.
+// It returns false if h does not belong to the queue anymore.
+func (q *PriorityQueue[T]) Update(h *Handle[T], value T) bool {
+	if !q.owns(h) {
+		return false
+	}
+
+	h.value = value
+	if !q.down(h.index) {
+		q.up(h.index)
+	}
+
+	return true
+}
+
+// Remove deletes h from the queue.
+// It returns false if h does not belong to the queue anymore.
+func (q *PriorityQueue[T]) Remove(h *Handle[T]) bool {
+	if !q.owns(h) {
+		return false
+	}
+
+	q.remove(h.index)
+
+	return true
+}
+
+func (q *PriorityQueue[T]) Len() int {
+	return len(q.items)
+}
+
+func (q *PriorityQueue[T]) Empty() bool {
+	return len(q.items) == 0
+}
+
+func (q *PriorityQueue[T]) owns(h *Handle[T]) bool {
+	return h != nil && h.index >= 0 && h.index < len(q.items) && q.items[h.index] == h
+}
+
+func (q *PriorityQueue[T]) remove(i int) {
+	last := len(q.items) - 1
+	removed := q.items[i]
+
+	if i != last {
+		q.swap(i, last)
+	}
+
+	q.items[last] = nil
+	q.items = q.items[:last]
+	removed.index = -1
+
+	if i != last && !q.down(i) {
+		q.up(i)
+	}
+}
+
+func (q *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(q.items[i].value, q.items[parent].value) {
+			break
+		}
+
+		q.swap(i, parent)
+		i = parent
+	}
+}
+
+// down reports whether the element at i moved.
+func (q *PriorityQueue[T]) down(i int) bool {
+	start := i
+
+	for {
+		smallest := i
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(q.items) && q.less(q.items[child].value, q.items[smallest].value) {
+				smallest = child
+			}
+		}
+
+		if smallest == i {
+			return i != start
+		}
+
+		q.swap(i, smallest)
+		i = smallest
+	}
+}
+
+func (q *PriorityQueue[T]) swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type delayed[T any] struct {
+	value    T
+	deadline time.Time
+	seq      uint64
+}
+
+// DelayQueue releases elements once their deadline has passed, earliest deadline first.
+// Elements with equal deadlines are released in push order. DelayQueue is not thread-safe.
+type DelayQueue[T any] struct {
+	clock Clock
+	items *PriorityQueue[delayed[T]]
+	seq   uint64
+}
+
+// NewDelayQueue uses the system clock when clock is nil.
+func NewDelayQueue[T any](clock Clock) *DelayQueue[T] {
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	return &DelayQueue[T]{
+		clock: clock,
+		items: NewPriorityQueue(func(a, b delayed[T]) bool {
+			if a.deadline.Equal(b.deadline) {
+				return a.seq < b.seq
+			}
+
+			return a.deadline.Before(b.deadline)
+		}),
+	}
+}
+
+func (q *DelayQueue[T]) Push(value T, deadline time.Time) {
+	q.items.Push(delayed[T]{value: value, deadline: deadline, seq: q.seq})
+	q.seq++
+}
+
+// Pop returns the element with the earliest deadline if that deadline has passed.
+func (q *DelayQueue[T]) Pop() (T, bool) {
+	if _, ok := q.Front(); !ok {
+		var zero T
+		return zero, false
+	}
+
+	item, _ := q.items.Pop()
+
+	return item.value, true
+}
+
+// Front returns the element with the earliest deadline if that deadline has passed.
+func (q *DelayQueue[T]) Front() (T, bool) {
+	item, ok := q.items.Front()
+	if !ok || item.deadline.After(q.clock.Now()) {
+		var zero T
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+// NextDeadline returns the earliest deadline, whether or not it has passed.
+func (q *DelayQueue[T]) NextDeadline() (time.Time, bool) {
+	item, ok := q.items.Front()
+	return item.deadline, ok
+}
+
+// Len counts all elements, including the ones whose deadline has not passed yet.
+func (q *DelayQueue[T]) Len() int {
+	return q.items.Len()
+}
+
+// Empty reports whether no element is ready, even if some are still waiting
+// for their deadline. Use Len or NextDeadline to find the waiting ones.
+func (q *DelayQueue[T]) Empty() bool {
+	_, ok := q.Front()
+	return !ok
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestPriorityQueue(t *testing.T) {
+	queue := NewPriorityQueue(func(a, b int) bool { return a < b })
+
+	assertNotFound[int](t)(queue.Pop())
+	assertNotFound[int](t)(queue.Front())
+	assert.True(t, queue.Empty())
+
+	for _, v := range []int{5, 3, 8, 1, 9, 2} {
+		queue.Push(v)
+	}
+
+	assert.Equal(t, 6, queue.Len())
+	assertFound(t, 1)(queue.Front())
+
+	for _, v := range []int{1, 2, 3, 5, 8, 9} {
+		assertFound(t, v)(queue.Pop())
+	}
+
+	assert.True(t, queue.Empty())
+}
+
+func TestPriorityQueueUpdateAndRemove(t *testing.T) {
+	type task struct {
+		name     string
+		priority int
+	}
+
+	queue := NewPriorityQueue(func(a, b task) bool { return a.priority > b.priority })
+
+	low := queue.Push(task{name: "low", priority: 1})
+	mid := queue.Push(task{name: "mid", priority: 5})
+	high := queue.Push(task{name: "high", priority: 10})
+	other := queue.Push(task{name: "other", priority: 3})
+
+	assertFound(t, task{name: "high", priority: 10})(queue.Front())
+
+	assert.True(t, queue.Update(low, task{name: "low", priority: 20}))
+	assertFound(t, task{name: "low", priority: 20})(queue.Front())
+
+	assert.True(t, queue.Update(low, task{name: "low", priority: 0}))
+	assertFound(t, task{name: "high", priority: 10})(queue.Front())
+
+	assert.True(t, queue.Remove(high))
+	assert.False(t, queue.Remove(high))
+	assert.False(t, queue.Update(high, task{}))
+	assert.Equal(t, "high", high.Value().name)
+
+	assertFound(t, task{name: "mid", priority: 5})(queue.Pop())
+	assert.False(t, queue.Remove(mid))
+
+	assertFound(t, task{name: "other", priority: 3})(queue.Pop())
+	assertFound(t, task{name: "low", priority: 0})(queue.Pop())
+	assert.False(t, queue.Update(other, task{}))
+	assert.False(t, queue.Remove(nil))
+
+	foreign := NewPriorityQueue(func(a, b task) bool { return a.priority > b.priority })
+	foreignHandle := foreign.Push(task{})
+	queue.Push(task{})
+	assert.False(t, queue.Remove(foreignHandle))
+}
+
+func TestPriorityQueueRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	queue := NewPriorityQueue(func(a, b int) bool { return a < b })
+
+	handles := make(map[*Handle[int]]struct{})
+	for range 1000 {
+		switch op := r.Intn(4); {
+		case op <= 1 || len(handles) == 0:
+			handles[queue.Push(r.Intn(1000))] = struct{}{}
+		case op == 2:
+			for h := range handles {
+				assert.True(t, queue.Update(h, r.Intn(1000)))
+				break
+			}
+		default:
+			for h := range handles {
+				assert.True(t, queue.Remove(h))
+				delete(handles, h)
+				break
+			}
+		}
+	}
+
+	var expected []int
+	for h := range handles {
+		expected = append(expected, h.Value())
+	}
+	slices.Sort(expected)
+
+	var actual []int
+	for v, ok := queue.Pop(); ok; v, ok = queue.Pop() {
+		actual = append(actual, v)
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestDelayQueue(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := NewDelayQueue[string](clock)
+
+	_, ok := queue.NextDeadline()
+	assert.False(t, ok)
+
+	queue.Push("later", clock.now.Add(2*time.Second))
+	queue.Push("soon", clock.now.Add(time.Second))
+	queue.Push("also soon", clock.now.Add(time.Second))
+	queue.Push("now", clock.now)
+
+	assert.Equal(t, 4, queue.Len())
+
+	assertFound(t, "now")(queue.Pop())
+	assertNotFound[string](t)(queue.Front())
+	assertNotFound[string](t)(queue.Pop())
+
+	deadline, ok := queue.NextDeadline()
+	assert.True(t, ok)
+	assert.Equal(t, clock.now.Add(time.Second), deadline)
+
+	clock.Advance(time.Second)
+	assertFound(t, "soon")(queue.Front())
+	assertFound(t, "soon")(queue.Pop())
+	assertFound(t, "also soon")(queue.Pop())
+	assertNotFound[string](t)(queue.Pop())
+	assert.True(t, queue.Empty())
+	assert.Equal(t, 1, queue.Len())
+
+	clock.Advance(time.Hour)
+	assertFound(t, "later")(queue.Pop())
+	assert.True(t, queue.Empty())
+	assert.Zero(t, queue.Len())
+}
+
+func TestDelayQueueSystemClock(t *testing.T) {
+	queue := NewDelayQueue[int](nil)
+
+	queue.Push(1, time.Now().Add(-time.Millisecond))
+	queue.Push(2, time.Now().Add(time.Hour))
+
+	assertFound(t, 1)(queue.Pop())
+	assertNotFound[int](t)(queue.Pop())
+	assert.Equal(t, 1, queue.Len())
+}
+
+func TestQueueInterface(t *testing.T) {
+	circular := NewCircularQueue[int](3)
+	priority := NewPriorityQueue(func(a, b int) bool { return a < b })
+	delay := NewDelayQueue[int](&fakeClock{})
+
+	for _, v := range []int{3, 1, 2} {
+		circular.Push(v)
+		priority.Push(v)
+		delay.Push(v, time.Time{})
+	}
+
+	queues := map[string]struct {
+		queue    Queue[int]
+		expected []int
+	}{
+		"circular": {queue: &circular, expected: []int{3, 1, 2}},
+		"priority": {queue: priority, expected: []int{1, 2, 3}},
+		"delay":    {queue: delay, expected: []int{3, 1, 2}},
+	}
+
+	for name, test := range queues {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, 3, test.queue.Len())
+
+			var actual []int
+			for !test.queue.Empty() {
+				v, ok := test.queue.Pop()
+				assert.True(t, ok)
+				actual = append(actual, v)
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestDelayQueueDrainWithWaiting(t *testing.T) {
+	clock := &fakeClock{}
+	var queue Queue[int] = NewDelayQueue[int](clock)
+
+	delay := queue.(*DelayQueue[int])
+	delay.Push(1, clock.now)
+	delay.Push(2, clock.now.Add(time.Minute))
+
+	var drained []int
+	for !queue.Empty() {
+		v, ok := queue.Pop()
+		assert.True(t, ok)
+		drained = append(drained, v)
+	}
+
+	assert.Equal(t, []int{1}, drained)
+	assert.Equal(t, 1, queue.Len())
+
+	clock.Advance(time.Minute)
+	assert.False(t, queue.Empty())
+	assertFound(t, 2)(queue.Pop())
+}
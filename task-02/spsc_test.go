@@ -0,0 +1,267 @@
+package main
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go spsc_test.go
+// go test -bench=. homework_test.go spsc_test.go
+
+const cacheLineSize = 64
+
+// SPSCQueue is a lock-free ring that is safe for exactly one producer
+// goroutine calling Push and one consumer goroutine calling Pop.
+// The capacity is rounded up to a power of two.
+type SPSCQueue[T any] struct {
+	values []T
+	mask   uint64
+
+	_          [cacheLineSize]byte
+	head       atomic.Uint64
+	cachedTail uint64
+
+	_          [cacheLineSize - 16]byte
+	tail       atomic.Uint64
+	cachedHead uint64
+
+	_ [cacheLineSize - 16]byte
+}
+
+func NewSPSCQueue[T any](size int) *SPSCQueue[T] {
+	capacity := 1
+	if size > 1 {
+		capacity = 1 << bits.Len(uint(size-1))
+	}
+
+	return &SPSCQueue[T]{
+		values: make([]T, capacity),
+		mask:   uint64(capacity - 1),
+	}
+}
+
+// Push must only be called from the producer goroutine.
+func (q *SPSCQueue[T]) Push(value T) bool {
+	tail := q.tail.Load()
+
+	if tail-q.cachedHead == uint64(len(q.values)) {
+		q.cachedHead = q.head.Load()
+		if tail-q.cachedHead == uint64(len(q.values)) {
+			return false
+		}
+	}
+
+	q.values[tail&q.mask] = value
+	q.tail.Store(tail + 1)
+
+	return true
+}
+
+// Pop must only be called from the consumer goroutine.
+func (q *SPSCQueue[T]) Pop() (T, bool) {
+	head := q.head.Load()
+
+	if head == q.cachedTail {
+		q.cachedTail = q.tail.Load()
+		if head == q.cachedTail {
+			var zero T
+			return zero, false
+		}
+	}
+
+	value := q.values[head&q.mask]
+
+	var zero T
+	q.values[head&q.mask] = zero
+	q.head.Store(head + 1)
+
+	return value, true
+}
+
+// Len is exact only when called from the producer or the consumer goroutine.
+func (q *SPSCQueue[T]) Len() int {
+	head := q.head.Load()
+	tail := q.tail.Load()
+
+	return int(tail - head)
+}
+
+func (q *SPSCQueue[T]) Cap() int {
+	return len(q.values)
+}
+
+func TestSPSCQueue(t *testing.T) {
+	queue := NewSPSCQueue[int](3)
+	assert.Equal(t, 4, queue.Cap())
+
+	assertNotFound[int](t)(queue.Pop())
+
+	for i := range 4 {
+		assert.True(t, queue.Push(i))
+	}
+
+	assert.False(t, queue.Push(4))
+	assert.Equal(t, 4, queue.Len())
+
+	assertFound(t, 0)(queue.Pop())
+	assertFound(t, 1)(queue.Pop())
+	assert.True(t, queue.Push(4))
+	assert.True(t, queue.Push(5))
+	assert.False(t, queue.Push(6))
+
+	for i := 2; i <= 5; i++ {
+		assertFound(t, i)(queue.Pop())
+	}
+
+	assertNotFound[int](t)(queue.Pop())
+	assert.Zero(t, queue.Len())
+}
+
+func TestSPSCQueueCapacity(t *testing.T) {
+	tests := map[string]struct {
+		size     int
+		capacity int
+	}{
+		"negative":       {size: -1, capacity: 1},
+		"zero":           {size: 0, capacity: 1},
+		"one":            {size: 1, capacity: 1},
+		"power of two":   {size: 8, capacity: 8},
+		"round up":       {size: 9, capacity: 16},
+		"large round up": {size: 1000, capacity: 1024},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.capacity, NewSPSCQueue[int](test.size).Cap())
+		})
+	}
+}
+
+func TestSPSCQueuePopReleasesValue(t *testing.T) {
+	queue := NewSPSCQueue[*int](1)
+	value := new(int)
+
+	assert.True(t, queue.Push(value))
+	assertFound(t, value)(queue.Pop())
+	assert.Nil(t, queue.values[0])
+}
+
+func TestSPSCQueueConcurrent(t *testing.T) {
+	const total = 100000
+
+	queue := NewSPSCQueue[int](64)
+
+	go func() {
+		for i := 0; i < total; {
+			if queue.Push(i) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	for expected := 0; expected < total; {
+		value, ok := queue.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+
+		if value != expected {
+			t.Fatalf("expected %d, got %d", expected, value)
+		}
+
+		expected++
+	}
+
+	assertNotFound[int](t)(queue.Pop())
+}
+
+const benchmarkQueueSize = 1024
+
+func BenchmarkSPSCQueue(b *testing.B) {
+	queue := NewSPSCQueue[int](benchmarkQueueSize)
+
+	benchmarkProducerConsumer(b,
+		func(v int) bool { return queue.Push(v) },
+		func() (int, bool) { return queue.Pop() },
+	)
+}
+
+func BenchmarkMutexCircularQueue(b *testing.B) {
+	var mu sync.Mutex
+	queue := NewCircularQueue[int](benchmarkQueueSize)
+
+	benchmarkProducerConsumer(b,
+		func(v int) bool {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return queue.Push(v)
+		},
+		func() (int, bool) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return queue.Pop()
+		},
+	)
+}
+
+func BenchmarkChannel(b *testing.B) {
+	ch := make(chan int, benchmarkQueueSize)
+
+	benchmarkProducerConsumer(b,
+		func(v int) bool {
+			select {
+			case ch <- v:
+				return true
+			default:
+				return false
+			}
+		},
+		func() (int, bool) {
+			select {
+			case v := <-ch:
+				return v, true
+			default:
+				return 0, false
+			}
+		},
+	)
+}
+
+func benchmarkProducerConsumer(b *testing.B, push func(int) bool, pop func() (int, bool)) {
+	done := make(chan struct{})
+	n := b.N
+
+	b.ResetTimer()
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < n; {
+			if push(i) {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	for received := 0; received < n; {
+		if _, ok := pop(); ok {
+			received++
+		} else {
+			runtime.Gosched()
+		}
+	}
+
+	<-done
+}
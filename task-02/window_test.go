@@ -0,0 +1,232 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/constraints"
+)
+
+// go test -v homework_test.go window_test.go
+
+type sample[T any] struct {
+	seq   int
+	value T
+}
+
+// WindowStats keeps running statistics over the last size pushed values.
+// Every push is O(1) amortized. WindowStats is not thread-safe.
+type WindowStats[T constraints.Integer | constraints.Float] struct {
+	window CircularQueue[T]
+	mins   CircularQueue[sample[T]]
+	maxs   CircularQueue[sample[T]]
+	seq    int
+	sum    T
+	mean   float64
+	m2     float64
+}
+
+// NewWindowStats creates statistics over a window of size values; size is at least 1.
+func NewWindowStats[T constraints.Integer | constraints.Float](size int) *WindowStats[T] {
+	size = max(size, 1)
+
+	return &WindowStats[T]{
+		window: NewCircularQueue[T](size, WithFullPolicy(OverwriteWhenFull)),
+		mins:   NewCircularQueue[sample[T]](size),
+		maxs:   NewCircularQueue[sample[T]](size),
+	}
+}
+
+func (s *WindowStats[T]) Push(value T) {
+	x := float64(value)
+
+	if old, evicted := s.window.PushEvict(value); evicted {
+		n := float64(s.window.Len())
+		prevMean := s.mean
+
+		s.sum += value - old
+		s.mean += (x - float64(old)) / n
+		s.m2 += (x - float64(old)) * (x - s.mean + float64(old) - prevMean)
+		s.m2 = max(s.m2, 0)
+	} else {
+		n := float64(s.window.Len())
+		delta := x - s.mean
+
+		s.sum += value
+		s.mean += delta / n
+		s.m2 += delta * (x - s.mean)
+	}
+
+	s.pushExtremum(&s.mins, value, func(back, value T) bool { return back >= value })
+	s.pushExtremum(&s.maxs, value, func(back, value T) bool { return back <= value })
+	s.seq++
+}
+
+// pushExtremum keeps a deque of candidates ordered so that the front is the extremum.
+func (s *WindowStats[T]) pushExtremum(deque *CircularQueue[sample[T]], value T, dominated func(back, value T) bool) {
+	for front, ok := deque.Front(); ok && front.seq <= s.seq-s.window.Cap(); front, ok = deque.Front() {
+		deque.Pop()
+	}
+
+	for back, ok := deque.Back(); ok && dominated(back.value, value); back, ok = deque.Back() {
+		deque.PopBack()
+	}
+
+	deque.Push(sample[T]{seq: s.seq, value: value})
+}
+
+func (s *WindowStats[T]) Len() int {
+	return s.window.Len()
+}
+
+// Sum is accumulated in T, so integer windows may overflow.
+func (s *WindowStats[T]) Sum() T {
+	return s.sum
+}
+
+func (s *WindowStats[T]) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the population variance of the window.
+func (s *WindowStats[T]) Variance() float64 {
+	if s.window.Empty() {
+		return 0
+	}
+
+	return s.m2 / float64(s.window.Len())
+}
+
+func (s *WindowStats[T]) Min() (T, bool) {
+	front, ok := s.mins.Front()
+	return front.value, ok
+}
+
+func (s *WindowStats[T]) Max() (T, bool) {
+	front, ok := s.maxs.Front()
+	return front.value, ok
+}
+
+func TestWindowStatsInt(t *testing.T) {
+	stats := NewWindowStats[int](3)
+
+	assert.Zero(t, stats.Len())
+	assert.Zero(t, stats.Sum())
+	assert.Zero(t, stats.Mean())
+	assert.Zero(t, stats.Variance())
+	assertNotFound[int](t)(stats.Min())
+	assertNotFound[int](t)(stats.Max())
+
+	stats.Push(4)
+	assert.Equal(t, 4, stats.Sum())
+	assert.Equal(t, 4.0, stats.Mean())
+	assert.Zero(t, stats.Variance())
+	assertFound(t, 4)(stats.Min())
+	assertFound(t, 4)(stats.Max())
+
+	stats.Push(1)
+	stats.Push(7)
+	assert.Equal(t, 3, stats.Len())
+	assert.Equal(t, 12, stats.Sum())
+	assert.InDelta(t, 4.0, stats.Mean(), 1e-9)
+	assert.InDelta(t, 6.0, stats.Variance(), 1e-9)
+	assertFound(t, 1)(stats.Min())
+	assertFound(t, 7)(stats.Max())
+
+	stats.Push(3)
+	assert.Equal(t, 3, stats.Len())
+	assert.Equal(t, 11, stats.Sum())
+	assert.InDelta(t, 11.0/3, stats.Mean(), 1e-9)
+	assert.InDelta(t, 56.0/9, stats.Variance(), 1e-9)
+	assertFound(t, 1)(stats.Min())
+	assertFound(t, 7)(stats.Max())
+
+	stats.Push(5)
+	assertFound(t, 3)(stats.Min())
+	assertFound(t, 7)(stats.Max())
+
+	stats.Push(2)
+	assertFound(t, 2)(stats.Min())
+	assertFound(t, 5)(stats.Max())
+}
+
+func TestWindowStatsFloat(t *testing.T) {
+	stats := NewWindowStats[float64](2)
+
+	stats.Push(-1.5)
+	stats.Push(2.5)
+	assert.InDelta(t, 1.0, stats.Sum(), 1e-9)
+	assert.InDelta(t, 0.5, stats.Mean(), 1e-9)
+	assert.InDelta(t, 4.0, stats.Variance(), 1e-9)
+
+	stats.Push(2.5)
+	assert.InDelta(t, 5.0, stats.Sum(), 1e-9)
+	assert.InDelta(t, 2.5, stats.Mean(), 1e-9)
+	assert.InDelta(t, 0.0, stats.Variance(), 1e-9)
+	assertFound(t, 2.5)(stats.Min())
+	assertFound(t, 2.5)(stats.Max())
+}
+
+func TestWindowStatsMinimalSize(t *testing.T) {
+	stats := NewWindowStats[uint8](0)
+
+	stats.Push(10)
+	stats.Push(3)
+
+	assert.Equal(t, 1, stats.Len())
+	assert.Equal(t, uint8(3), stats.Sum())
+	assertFound(t, uint8(3))(stats.Min())
+	assertFound(t, uint8(3))(stats.Max())
+}
+
+func TestWindowStatsRandom(t *testing.T) {
+	t.Run("int64", func(t *testing.T) {
+		testWindowStatsRandom(t, func(r *rand.Rand) int64 { return r.Int63n(2000) - 1000 })
+	})
+
+	t.Run("float64", func(t *testing.T) {
+		testWindowStatsRandom(t, func(r *rand.Rand) float64 { return r.NormFloat64() * 100 })
+	})
+}
+
+func testWindowStatsRandom[T constraints.Integer | constraints.Float](t *testing.T, random func(*rand.Rand) T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{1, 2, 7, 32} {
+		stats := NewWindowStats[T](size)
+		var values []T
+
+		for range 500 {
+			value := random(r)
+			stats.Push(value)
+
+			values = append(values, value)
+			window := values[max(0, len(values)-size):]
+
+			var sum T
+			minValue, maxValue := window[0], window[0]
+			for _, v := range window {
+				sum += v
+				minValue = min(minValue, v)
+				maxValue = max(maxValue, v)
+			}
+
+			mean := float64(sum) / float64(len(window))
+
+			var variance float64
+			for _, v := range window {
+				variance += (float64(v) - mean) * (float64(v) - mean)
+			}
+			variance /= float64(len(window))
+
+			assert.Equal(t, len(window), stats.Len())
+			assert.InDelta(t, float64(sum), float64(stats.Sum()), 1e-6)
+			assert.InDelta(t, mean, stats.Mean(), 1e-6)
+			assert.InDelta(t, variance, stats.Variance(), 1e-6*math.Max(1, variance))
+			assertFound(t, minValue)(stats.Min())
+			assertFound(t, maxValue)(stats.Max())
+		}
+	}
+}
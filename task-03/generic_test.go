@@ -0,0 +1,348 @@
+package main
+
+import (
+	"iter"
+	"maps"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go generic_test.go
+
+// COWSlice shares its elements between clones until one of them writes.
+// COWSlice is not thread-safe.
+type COWSlice[T any] struct {
+	data []T
+	ref  cowRef
+}
+
+// NewCOWSlice shares values without copying.
+func NewCOWSlice[T any](values []T) *COWSlice[T] {
+	s := &COWSlice[T]{data: values}
+	s.ref.attach(nil)
+
+	return s
+}
+
+func (s *COWSlice[T]) Clone() *COWSlice[T] {
+	if !s.ref.valid() {
+		return nil
+	}
+
+	clone := &COWSlice[T]{data: s.data}
+	clone.ref.attach(s.ref.refs)
+
+	return clone
+}
+
+func (s *COWSlice[T]) Close() {
+	s.ref.release()
+	s.data = nil
+}
+
+func (s *COWSlice[T]) Len() int {
+	return len(s.data)
+}
+
+func (s *COWSlice[T]) At(index int) (T, bool) {
+	if index < 0 || index >= len(s.data) {
+		var zero T
+		return zero, false
+	}
+
+	return s.data[index], true
+}
+
+func (s *COWSlice[T]) All() iter.Seq2[int, T] {
+	return slices.All(s.data)
+}
+
+func (s *COWSlice[T]) Set(index int, value T) bool {
+	if !s.ref.valid() || index < 0 || index >= len(s.data) {
+		return false
+	}
+
+	if s.ref.shared() {
+		s.data = slices.Clone(s.data)
+		s.ref.detach()
+	}
+
+	s.data[index] = value
+
+	return true
+}
+
+// Delete removes the element at index, shifting the following ones.
+func (s *COWSlice[T]) Delete(index int) bool {
+	if !s.ref.valid() || index < 0 || index >= len(s.data) {
+		return false
+	}
+
+	if s.ref.shared() {
+		data := make([]T, 0, len(s.data)-1)
+		data = append(data, s.data[:index]...)
+		s.data = append(data, s.data[index+1:]...)
+		s.ref.detach()
+	} else {
+		s.data = slices.Delete(s.data, index, index+1)
+	}
+
+	return true
+}
+
+// COWMap shares its entries between clones until one of them writes.
+// COWMap is not thread-safe.
+type COWMap[K comparable, V any] struct {
+	data map[K]V
+	ref  cowRef
+}
+
+// NewCOWMap shares entries without copying; a nil map is replaced with an empty one.
+func NewCOWMap[K comparable, V any](entries map[K]V) *COWMap[K, V] {
+	if entries == nil {
+		entries = make(map[K]V)
+	}
+
+	m := &COWMap[K, V]{data: entries}
+	m.ref.attach(nil)
+
+	return m
+}
+
+func (m *COWMap[K, V]) Clone() *COWMap[K, V] {
+	if !m.ref.valid() {
+		return nil
+	}
+
+	clone := &COWMap[K, V]{data: m.data}
+	clone.ref.attach(m.ref.refs)
+
+	return clone
+}
+
+func (m *COWMap[K, V]) Close() {
+	m.ref.release()
+	m.data = nil
+}
+
+func (m *COWMap[K, V]) Len() int {
+	return len(m.data)
+}
+
+func (m *COWMap[K, V]) Get(key K) (V, bool) {
+	value, ok := m.data[key]
+	return value, ok
+}
+
+// All iterates over the entries in unspecified order.
+func (m *COWMap[K, V]) All() iter.Seq2[K, V] {
+	return maps.All(m.data)
+}
+
+func (m *COWMap[K, V]) Set(key K, value V) bool {
+	if !m.ref.valid() {
+		return false
+	}
+
+	m.detach()
+	m.data[key] = value
+
+	return true
+}
+
+// Delete reports whether the key was present. Deleting
+// a missing key doesn't copy a shared map.
+func (m *COWMap[K, V]) Delete(key K) bool {
+	if _, ok := m.data[key]; !ok || !m.ref.valid() {
+		return false
+	}
+
+	m.detach()
+	delete(m.data, key)
+
+	return true
+}
+
+// detach makes m the sole owner of its entries, copying them if they are shared.
+func (m *COWMap[K, V]) detach() {
+	if m.ref.shared() {
+		m.data = maps.Clone(m.data)
+		m.ref.detach()
+	}
+}
+
+func mapPointer[K comparable, V any](m map[K]V) unsafe.Pointer {
+	return reflect.ValueOf(m).UnsafePointer()
+}
+
+func TestCOWSlice(t *testing.T) {
+	values := []int{1, 2, 3, 4}
+	slice := NewCOWSlice(values)
+	defer slice.Close()
+
+	clone := slice.Clone()
+	defer clone.Close()
+
+	assert.Equal(t, int64(2), slice.ref.refs.Load())
+	assert.Same(t, unsafe.SliceData(values), unsafe.SliceData(clone.data))
+
+	assertFound(t, 3)(clone.At(2))
+	assertNotFound[int](t)(clone.At(4))
+	assertNotFound[int](t)(clone.At(-1))
+	assert.Equal(t, 4, clone.Len())
+
+	assert.False(t, clone.Set(4, 0))
+	assert.Same(t, unsafe.SliceData(values), unsafe.SliceData(clone.data))
+
+	assert.True(t, clone.Set(0, 10))
+	assert.Equal(t, []int{10, 2, 3, 4}, clone.data)
+	assert.Equal(t, []int{1, 2, 3, 4}, values)
+	assert.Equal(t, int64(1), slice.ref.refs.Load())
+	assert.Equal(t, int64(1), clone.ref.refs.Load())
+
+	// 1 reference - don't need to copy during update
+	previous := unsafe.SliceData(clone.data)
+	assert.True(t, clone.Set(1, 20))
+	assert.Same(t, previous, unsafe.SliceData(clone.data))
+
+	var collected []int
+	for i, v := range clone.All() {
+		assert.Equal(t, clone.data[i], v)
+		collected = append(collected, v)
+	}
+	assert.Equal(t, []int{10, 20, 3, 4}, collected)
+}
+
+func TestCOWSlice_Delete(t *testing.T) {
+	values := []string{"a", "b", "c"}
+	slice := NewCOWSlice(values)
+	defer slice.Close()
+
+	clone := slice.Clone()
+
+	assert.False(t, clone.Delete(3))
+	assert.True(t, clone.Delete(1))
+	assert.Equal(t, []string{"a", "c"}, clone.data)
+	assert.Equal(t, []string{"a", "b", "c"}, values)
+	assert.Equal(t, int64(1), slice.ref.refs.Load())
+
+	clone.Close()
+
+	// the sole owner deletes in place
+	assert.True(t, slice.Delete(0))
+	assert.Equal(t, []string{"b", "c"}, slice.data)
+	assert.Same(t, unsafe.SliceData(values), unsafe.SliceData(slice.data))
+	assert.Equal(t, []string{"b", "c", ""}, values)
+}
+
+func TestCOWSlice_NilSafety(t *testing.T) {
+	var s COWSlice[int]
+
+	assert.Zero(t, s.Len())
+	assertNotFound[int](t)(s.At(0))
+	assert.False(t, s.Set(0, 1))
+	assert.False(t, s.Delete(0))
+	assert.Nil(t, s.Clone())
+
+	assert.NotPanics(t, func() {
+		s.Close()
+		s.Close()
+	})
+}
+
+func TestCOWMap(t *testing.T) {
+	entries := map[string]int{"timeout": 30, "retries": 3}
+	config := NewCOWMap(entries)
+	defer config.Close()
+
+	snapshot := config.Clone()
+	defer snapshot.Close()
+
+	assert.Equal(t, mapPointer(entries), mapPointer(snapshot.data))
+	assertFound(t, 30)(snapshot.Get("timeout"))
+	assertNotFound[int](t)(snapshot.Get("missing"))
+	assert.Equal(t, 2, snapshot.Len())
+
+	assert.False(t, config.Delete("missing"))
+	assert.Equal(t, mapPointer(entries), mapPointer(config.data))
+
+	assert.True(t, config.Set("timeout", 60))
+	assert.True(t, config.Delete("retries"))
+	assertFound(t, 60)(config.Get("timeout"))
+	assertNotFound[int](t)(config.Get("retries"))
+
+	assert.Equal(t, map[string]int{"timeout": 30, "retries": 3}, maps.Collect(snapshot.All()))
+	assert.Equal(t, map[string]int{"timeout": 30, "retries": 3}, entries)
+	assert.Equal(t, int64(1), config.ref.refs.Load())
+	assert.Equal(t, int64(1), snapshot.ref.refs.Load())
+
+	// 1 reference - don't need to copy during update
+	previous := mapPointer(config.data)
+	assert.True(t, config.Set("verbose", 1))
+	assert.Equal(t, previous, mapPointer(config.data))
+	assert.Equal(t, map[string]int{"timeout": 60, "verbose": 1}, maps.Collect(config.All()))
+}
+
+func TestCOWMap_NilSafety(t *testing.T) {
+	var m COWMap[string, int]
+
+	assert.Zero(t, m.Len())
+	assertNotFound[int](t)(m.Get("a"))
+	assert.False(t, m.Set("a", 1))
+	assert.False(t, m.Delete("a"))
+	assert.Nil(t, m.Clone())
+
+	assert.NotPanics(t, func() {
+		m.Close()
+		m.Close()
+	})
+
+	empty := NewCOWMap[string, int](nil)
+	defer empty.Close()
+
+	assert.True(t, empty.Set("a", 1))
+	assertFound(t, 1)(empty.Get("a"))
+}
+
+func TestCOWContainers_Cleanup(t *testing.T) {
+	slice := NewCOWSlice([]int{1})
+	defer slice.Close()
+
+	config := NewCOWMap(map[int]int{1: 1})
+	defer config.Close()
+
+	func() {
+		slice.Clone()
+		config.Clone().Set(2, 2)
+	}()
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return slice.ref.refs.Load() == 1 && config.ref.refs.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func assertFound[T any](t *testing.T, expected T) func(T, bool) {
+	return func(value T, ok bool) {
+		t.Helper()
+
+		assert.True(t, ok)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func assertNotFound[T any](t *testing.T) func(T, bool) {
+	return func(value T, ok bool) {
+		t.Helper()
+
+		var zero T
+		assert.False(t, ok)
+		assert.Equal(t, zero, value)
+	}
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"reflect"
 	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/stretchr/testify/assert"
@@ -11,51 +16,186 @@ import (
 
 // COWBuffer is not thread-safe.
 type COWBuffer struct {
-	data []byte
-	refs *int
+	data   []byte
+	ref    cowRef
+	pool   *COWPool
+	pooled bool // data is released to pool by the last Close
+	pinned bool
 }
 
-func newCOWBuffer(data []byte, refs *int) *COWBuffer {
-	b := &COWBuffer{
-		data: data,
-		refs: refs,
+// cowRef is one reference to data shared by copy-on-write containers.
+// It is released by Close or, if its container is collected without Close,
+// by a cleanup, so the counter is atomic. The cleanup of a reference with
+// an origin also reports it as a leak.
+type cowRef struct {
+	refs    *atomic.Int64
+	cleanup runtime.Cleanup
+	origin  *COWLeak
+}
+
+type cowCleanupArg struct {
+	refs   *atomic.Int64
+	origin *COWLeak
+}
+
+// attach makes r one more reference of refs, or the first one of a new counter if refs is nil.
+func (r *cowRef) attach(refs *atomic.Int64) {
+	if refs == nil {
+		refs = new(atomic.Int64)
 	}
 
-	runtime.SetFinalizer(b, func(b *COWBuffer) {
-		b.Close()
-	})
+	refs.Add(1)
+
+	r.refs = refs
+	r.cleanup.Stop()
+	r.cleanup = runtime.AddCleanup(r, func(arg cowCleanupArg) {
+		arg.refs.Add(-1)
+
+		if arg.origin != nil {
+			leakDetector.mu.Lock()
+			leakDetector.leaks = append(leakDetector.leaks, *arg.origin)
+			leakDetector.mu.Unlock()
+		}
+	}, cowCleanupArg{refs: refs, origin: r.origin})
+}
+
+func (r *cowRef) valid() bool {
+	return r.refs != nil
+}
+
+func (r *cowRef) shared() bool {
+	return r.refs.Load() > 1
+}
+
+// pin adds a reference that is never released, for data aliased by
+// a string: it stays shared, so every write copies it first.
+func (r *cowRef) pin() {
+	r.refs.Add(1)
+}
+
+// detach moves r to a new counter after its container has copied the data.
+func (r *cowRef) detach() {
+	r.refs.Add(-1)
+	r.attach(nil)
+}
+
+// release drops r and reports whether it was the last reference to the data.
+func (r *cowRef) release() bool {
+	r.cleanup.Stop()
+	runtime.KeepAlive(r)
+
+	last := r.refs != nil && r.refs.Add(-1) == 0
+	r.refs = nil
+
+	return last
+}
+
+type COWOption func(*COWBuffer)
+
+// WithPool makes the buffer and its clones take the copies they make on
+// write from pool and put them back once their last reference is closed.
+// Slices returned by Bytes of such a buffer must not outlive it.
+func WithPool(pool *COWPool) COWOption {
+	return func(b *COWBuffer) {
+		b.pool = pool
+	}
+}
+
+// COWPool reuses the copies that COWBuffer makes on write.
+// The zero value is ready to use. COWPool is thread-safe.
+type COWPool struct {
+	pool sync.Pool
+}
+
+func (p *COWPool) get(length, capacity int) []byte {
+	if data, ok := p.pool.Get().(*[]byte); ok && cap(*data) >= capacity {
+		return (*data)[:length]
+	}
+
+	return make([]byte, length, capacity)
+}
+
+func (p *COWPool) put(data []byte) {
+	p.pool.Put(&data)
+}
+
+// COWLeak describes a buffer that was garbage collected without Close.
+type COWLeak struct {
+	Stack string
+}
+
+var leakDetector struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	leaks   []COWLeak
+}
+
+// SetCOWLeakDetection enables recording the stack of every NewCOWBuffer and Clone
+// call, so that buffers collected without Close are reported by COWLeaks.
+func SetCOWLeakDetection(enabled bool) {
+	leakDetector.enabled.Store(enabled)
+}
+
+// COWLeaks returns the leaks reported since the previous call and forgets them.
+func COWLeaks() []COWLeak {
+	leakDetector.mu.Lock()
+	defer leakDetector.mu.Unlock()
+
+	leaks := leakDetector.leaks
+	leakDetector.leaks = nil
+
+	return leaks
+}
+
+func newCOWBuffer(data []byte, refs *atomic.Int64) *COWBuffer {
+	b := &COWBuffer{data: data}
+
+	if leakDetector.enabled.Load() {
+		b.ref.origin = &COWLeak{Stack: string(debug.Stack())}
+	}
+
+	b.ref.attach(refs)
 
 	return b
 }
 
-func NewCOWBuffer(data []byte) *COWBuffer {
-	refs := new(int)
-	*refs = 1
+func NewCOWBuffer(data []byte, opts ...COWOption) *COWBuffer {
+	b := newCOWBuffer(data, nil)
+	for _, opt := range opts {
+		opt(b)
+	}
 
-	return newCOWBuffer(data, refs)
+	return b
 }
 
 func (b *COWBuffer) Clone() *COWBuffer {
-	if b.refs == nil {
+	if !b.ref.valid() {
 		return nil
 	}
 
-	*b.refs++
+	return b.share(b.data)
+}
+
+// share returns a new buffer over data that counts as one more reference of b.
+func (b *COWBuffer) share(data []byte) *COWBuffer {
+	shared := newCOWBuffer(data, b.ref.refs)
+	shared.pool = b.pool
+	shared.pooled = b.pooled
+	shared.pinned = b.pinned
 
-	return newCOWBuffer(b.data, b.refs)
+	return shared
 }
 
 func (b *COWBuffer) Close() {
-	if b.refs != nil && *b.refs > 0 {
-		*b.refs--
+	if b.ref.release() && b.pooled {
+		b.pool.put(b.data)
 	}
 
-	b.refs = nil
 	b.data = nil
 }
 
 func (b *COWBuffer) Update(index int, value byte) bool {
-	if b.refs == nil || len(b.data) == 0 {
+	if !b.ref.valid() || len(b.data) == 0 {
 		return false
 	}
 
@@ -63,19 +203,106 @@ func (b *COWBuffer) Update(index int, value byte) bool {
 		return false
 	}
 
-	if *b.refs > 1 {
-		*b.refs--
-		newData := make([]byte, len(b.data))
-		copy(newData, b.data)
-		*b = *NewCOWBuffer(newData)
+	b.detach(len(b.data))
+	b.data[index] = value
+
+	return true
+}
+
+// WriteAt copies p into the buffer starting at off. The whole range must fit into the buffer.
+func (b *COWBuffer) WriteAt(off int, p []byte) bool {
+	if !b.ref.valid() || off < 0 || off > len(b.data) || len(p) > len(b.data)-off {
+		return false
 	}
 
-	b.data[index] = value
+	if len(p) == 0 {
+		return true
+	}
+
+	b.detach(len(b.data))
+	copy(b.data[off:], p)
 
 	return true
 }
 
+func (b *COWBuffer) Append(p []byte) bool {
+	if !b.ref.valid() {
+		return false
+	}
+
+	if len(p) == 0 {
+		return true
+	}
+
+	b.detach(len(b.data) + len(p))
+	b.data = append(b.data, p...)
+
+	return true
+}
+
+// Truncate shortens the buffer to n bytes. It never copies: bytes beyond n
+// are only reused by a later Append once the buffer is not shared anymore.
+func (b *COWBuffer) Truncate(n int) bool {
+	if !b.ref.valid() || n < 0 || n > len(b.data) {
+		return false
+	}
+
+	b.data = b.data[:n]
+
+	return true
+}
+
+// Bytes returns the current data. The slice must not be modified;
+// its capacity is limited so that appending to it always reallocates.
+func (b *COWBuffer) Bytes() []byte {
+	return b.data[:len(b.data):len(b.data)]
+}
+
+// Slice returns a view of the bytes in [from, to) that shares the data and
+// its reference counter with b, so writing through any of them copies first.
+// The view must be closed like a clone; it returns nil for an invalid range.
+func (b *COWBuffer) Slice(from, to int) *COWBuffer {
+	if !b.ref.valid() || from < 0 || from > to || to > len(b.data) {
+		return nil
+	}
+
+	// The view only holds a part of pooled data, so putting it back
+	// is left to the buffers that hold all of it.
+	view := b.share(b.data[from:to:to])
+	view.pooled = false
+
+	return view
+}
+
+// detach makes b the sole owner of its data, copying it into
+// a new slice with the given capacity if it is shared.
+func (b *COWBuffer) detach(capacity int) {
+	if !b.ref.shared() {
+		return
+	}
+
+	var newData []byte
+	if b.pool != nil {
+		newData = b.pool.get(len(b.data), capacity)
+	} else {
+		newData = make([]byte, len(b.data), capacity)
+	}
+	copy(newData, b.data)
+
+	b.data = newData
+	b.ref.detach()
+	b.pooled = b.pool != nil
+	b.pinned = false
+}
+
+// String returns the data without copying. Strings must be immutable, so
+// the data is pinned and the next write through any buffer sharing it copies first.
 func (b *COWBuffer) String() string {
+	if b.ref.valid() && !b.pinned && len(b.data) > 0 {
+		b.ref.pin()
+		b.pinned = true
+	}
+
 	return *(*string)(unsafe.Pointer(&b.data))
 }
 
@@ -111,8 +338,13 @@ func TestCOWBuffer(t *testing.T) {
 
 	copy1.Close()
 
-	previous := copy2.data
+	// the strings returned above pin the data, so it is copied once
+	pinned := copy2.data
 	copy2.Update(0, 'f')
+	assert.NotSame(t, unsafe.SliceData(pinned), unsafe.SliceData(copy2.data))
+
+	previous := copy2.data
+	copy2.Update(0, 'e')
 	current := copy2.data
 
 	// 1 reference - don't need to copy buffer during update
@@ -125,19 +357,19 @@ func TestCOWBuffer_CloseClose(t *testing.T) {
 	data := []byte{'a', 'b', 'c', 'd'}
 	buffer := NewCOWBuffer(data)
 
-	assert.NotNil(t, buffer.refs)
+	assert.NotNil(t, buffer.ref.refs)
 	assert.NotNil(t, buffer.data)
 
 	buffer.Close()
 
-	assert.Nil(t, buffer.refs)
+	assert.Nil(t, buffer.ref.refs)
 	assert.Nil(t, buffer.data)
 
 	assert.NotPanics(t, func() {
 		buffer.Close()
 	})
 
-	assert.Nil(t, buffer.refs)
+	assert.Nil(t, buffer.ref.refs)
 	assert.Nil(t, buffer.data)
 }
 
@@ -159,38 +391,308 @@ func TestCOWBuffer_NilSafety(t *testing.T) {
 	})
 }
 
-func TestCOWBuffer_Finalizer(t *testing.T) {
+func TestCOWBuffer_Cleanup(t *testing.T) {
 	runtime.GC()
 
-	var refs *int
+	var refs *atomic.Int64
 
 	{
 		data := []byte{'a', 'b', 'c', 'd'}
 		copy1 := NewCOWBuffer(data)
-		refs = copy1.refs
+		refs = copy1.ref.refs
 
-		assert.Equal(t, 1, *copy1.refs)
+		assert.Equal(t, int64(1), copy1.ref.refs.Load())
 
 		{
 			copy2 := copy1.Clone()
 
-			assert.Equal(t, 2, *copy1.refs)
-			assert.Equal(t, 2, *copy2.refs)
+			assert.Equal(t, int64(2), copy1.ref.refs.Load())
+			assert.Equal(t, int64(2), copy2.ref.refs.Load())
 
 			copy3 := copy2.Clone()
 
-			assert.Equal(t, 3, *copy1.refs)
-			assert.Equal(t, 3, *copy2.refs)
-			assert.Equal(t, 3, *copy3.refs)
+			assert.Equal(t, int64(3), copy1.ref.refs.Load())
+			assert.Equal(t, int64(3), copy2.ref.refs.Load())
+			assert.Equal(t, int64(3), copy3.ref.refs.Load())
 		}
 
+		// cleanups run asynchronously after the collection
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			return copy1.ref.refs.Load() == 1
+		}, time.Second, time.Millisecond)
+	}
+
+	assert.NotNil(t, refs)
+	assert.Eventually(t, func() bool {
 		runtime.GC()
+		return refs.Load() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestCOWBuffer_WriteAt(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 'd'}
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	clone := buffer.Clone()
+	defer clone.Close()
+
+	assert.False(t, buffer.WriteAt(-1, []byte{'x'}))
+	assert.False(t, buffer.WriteAt(3, []byte{'x', 'y'}))
+	assert.False(t, buffer.WriteAt(5, nil))
+	assert.True(t, buffer.WriteAt(4, nil))
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+
+	assert.True(t, buffer.WriteAt(1, []byte{'x', 'y'}))
+	assert.Equal(t, "axyd", string(buffer.Bytes()))
+	assert.Equal(t, "abcd", string(clone.Bytes()))
+	assert.NotSame(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(clone.data))
+
+	diverged := unsafe.SliceData(buffer.data)
+	assert.True(t, buffer.WriteAt(3, []byte{'z'}))
+	assert.Equal(t, "axyz", string(buffer.Bytes()))
+	assert.Same(t, diverged, unsafe.SliceData(buffer.data))
+
+	assert.True(t, clone.WriteAt(0, []byte{'q'}))
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(clone.data))
+	assert.Equal(t, "qbcd", string(clone.Bytes()))
+}
+
+func TestCOWBuffer_Append(t *testing.T) {
+	data := make([]byte, 2, 8)
+	copy(data, "ab")
+
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	clone := buffer.Clone()
+
+	assert.True(t, buffer.Append(nil))
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+
+	assert.True(t, buffer.Append([]byte("cd")))
+	assert.Equal(t, "abcd", string(buffer.Bytes()))
+	assert.Equal(t, "ab", string(clone.Bytes()))
+	assert.Equal(t, []byte{'a', 'b', 0, 0}, data[:4])
+	assert.NotSame(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+
+	clone.Close()
+
+	// 1 reference - appending within capacity doesn't copy
+	diverged := unsafe.SliceData(buffer.data)
+	assert.True(t, buffer.Truncate(3))
+	assert.True(t, buffer.Append([]byte("e")))
+	assert.Equal(t, "abce", string(buffer.Bytes()))
+	assert.Same(t, diverged, unsafe.SliceData(buffer.data))
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+}
+
+func TestCOWBuffer_Truncate(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 'd'}
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	clone := buffer.Clone()
+	defer clone.Close()
+
+	assert.False(t, buffer.Truncate(-1))
+	assert.False(t, buffer.Truncate(5))
+
+	assert.True(t, buffer.Truncate(2))
+	assert.Equal(t, "ab", buffer.String())
+	assert.Equal(t, "abcd", clone.String())
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+
+	assert.True(t, buffer.Append([]byte{'x'}))
+	assert.Equal(t, "abx", buffer.String())
+	assert.Equal(t, "abcd", clone.String())
+	assert.Equal(t, []byte{'a', 'b', 'c', 'd'}, data)
+}
+
+func TestCOWBuffer_Bytes(t *testing.T) {
+	data := make([]byte, 2, 4)
+	copy(data, "ab")
+
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	view := buffer.Bytes()
+	assert.Equal(t, []byte("ab"), view)
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(view))
+	assert.Equal(t, 2, cap(view))
+
+	_ = append(view, 'x')
+	assert.Equal(t, []byte{'a', 'b', 0, 0}, data[:4])
+
+	var empty COWBuffer
+	assert.Empty(t, empty.Bytes())
+	assert.False(t, empty.WriteAt(0, nil))
+	assert.False(t, empty.Append([]byte{'a'}))
+	assert.False(t, empty.Truncate(0))
+}
+
+func TestCOWBuffer_UpdateKeepsRefs(t *testing.T) {
+	buffer := NewCOWBuffer([]byte{'a', 'b'})
+	defer buffer.Close()
 
-		assert.Equal(t, 1, *copy1.refs)
+	clone := buffer.Clone()
+	defer clone.Close()
+
+	assert.True(t, buffer.Update(0, 'x'))
+
+	runtime.GC()
+	runtime.GC()
+
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+	assert.Equal(t, int64(1), clone.ref.refs.Load())
+}
+
+func TestCOWBuffer_LeakDetection(t *testing.T) {
+	SetCOWLeakDetection(true)
+	t.Cleanup(func() {
+		SetCOWLeakDetection(false)
+		COWLeaks()
+	})
+
+	runtime.GC()
+	COWLeaks()
+
+	closed := NewCOWBuffer([]byte{'a', 'b'})
+	leakCOWBuffer(closed)
+	closed.Close()
+
+	var leaks []COWLeak
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		leaks = append(leaks, COWLeaks()...)
+
+		return len(leaks) >= 2
+	}, time.Second, time.Millisecond)
+
+	assert.Len(t, leaks, 2)
+	for _, leak := range leaks {
+		assert.True(t, strings.Contains(leak.Stack, "leakCOWBuffer"), leak.Stack)
 	}
 
 	runtime.GC()
+	assert.Empty(t, COWLeaks())
+}
 
-	assert.NotNil(t, refs)
-	assert.Equal(t, 0, *refs)
+//go:noinline
+func leakCOWBuffer(b *COWBuffer) {
+	leaked := b.Clone()
+	leaked.Clone()
+	leaked.Update(0, 'x')
+}
+
+func TestCOWBuffer_LeakDetectionDisabled(t *testing.T) {
+	runtime.GC()
+	COWLeaks()
+
+	{
+		b := NewCOWBuffer([]byte{'a'})
+		assert.Nil(t, b.ref.origin)
+	}
+
+	runtime.GC()
+	runtime.GC()
+
+	assert.Empty(t, COWLeaks())
+}
+
+func TestCOWBuffer_Slice(t *testing.T) {
+	data := []byte("key=value")
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	assert.Nil(t, buffer.Slice(-1, 2))
+	assert.Nil(t, buffer.Slice(3, 2))
+	assert.Nil(t, buffer.Slice(0, 10))
+
+	key := buffer.Slice(0, 3)
+	value := buffer.Slice(4, 9)
+	defer value.Close()
+
+	assert.Equal(t, "key", string(key.Bytes()))
+	assert.Equal(t, "value", string(value.Bytes()))
+	assert.Same(t, &data[4], unsafe.SliceData(value.data))
+	assert.Equal(t, int64(3), buffer.ref.refs.Load())
+
+	assert.True(t, value.Update(0, 'V'))
+	assert.Equal(t, "Value", string(value.Bytes()))
+	assert.Equal(t, "key=value", string(buffer.Bytes()))
+	assert.Equal(t, "key=value", string(data))
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+
+	assert.True(t, buffer.Update(0, 'K'))
+	assert.Equal(t, "Key=value", string(buffer.Bytes()))
+	assert.Equal(t, "key", string(key.Bytes()))
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(key.data))
+
+	// appending to a view never overwrites the bytes behind it
+	assert.True(t, key.Append([]byte("s")))
+	assert.Equal(t, "keys", string(key.Bytes()))
+	assert.Equal(t, "key=value", string(data))
+	assert.Equal(t, int64(1), key.ref.refs.Load())
+
+	key.Close()
+
+	var closed COWBuffer
+	assert.Nil(t, closed.Slice(0, 0))
+}
+
+func TestCOWBuffer_StringIsImmutable(t *testing.T) {
+	buffer := NewCOWBuffer([]byte("abcd"))
+	defer buffer.Close()
+
+	str := buffer.String()
+	assert.Same(t, unsafe.SliceData(buffer.data), unsafe.StringData(str))
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+
+	// pinning happens once per data
+	assert.Equal(t, str, buffer.String())
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+
+	assert.True(t, buffer.Update(0, 'x'))
+	assert.Equal(t, "abcd", str)
+	assert.Equal(t, "xbcd", string(buffer.Bytes()))
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+
+	// writes to the new data don't copy until it is pinned again
+	previous := unsafe.SliceData(buffer.data)
+	assert.True(t, buffer.Update(1, 'y'))
+	assert.Same(t, previous, unsafe.SliceData(buffer.data))
+
+	truncated := buffer.String()
+	assert.True(t, buffer.Truncate(2))
+	assert.True(t, buffer.Append([]byte("zz")))
+	assert.True(t, buffer.WriteAt(0, []byte("q")))
+	assert.Equal(t, "xycd", truncated)
+	assert.Equal(t, "qyzz", string(buffer.Bytes()))
+
+	// clones share the pin of their data
+	clone := buffer.Clone()
+	cloned := clone.String()
+	buffer.Close()
+	clone.Close()
+	assert.Equal(t, "qyzz", cloned)
+
+	var pool COWPool
+	pooled := NewCOWBuffer([]byte("ab"), WithPool(&pool))
+	pooledClone := pooled.Clone()
+	pooledClone.Update(0, 'x')
+	str = pooledClone.String()
+	refs := pooledClone.ref.refs
+	pooledClone.Close()
+	pooled.Close()
+
+	// pinned data never returns to the pool
+	assert.Equal(t, int64(1), refs.Load())
+	assert.Equal(t, "xb", str)
+
+	var empty COWBuffer
+	assert.Equal(t, "", empty.String())
+	assert.False(t, empty.pinned)
 }
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go io_test.go
+// go test -bench=Allocator -benchmem homework_test.go io_test.go
+
+var (
+	ErrBufferClosed   = errors.New("cow buffer is closed")
+	ErrNegativeOffset = errors.New("negative offset")
+)
+
+var (
+	_ io.WriterTo   = (*COWBuffer)(nil)
+	_ io.ReaderFrom = (*COWBuffer)(nil)
+	_ io.Reader     = (*COWReader)(nil)
+	_ io.ReaderAt   = (*COWReader)(nil)
+	_ io.WriterTo   = (*COWReader)(nil)
+)
+
+func (b *COWBuffer) WriteTo(w io.Writer) (int64, error) {
+	if !b.ref.valid() {
+		return 0, ErrBufferClosed
+	}
+
+	if len(b.data) == 0 {
+		return 0, nil
+	}
+
+	n, err := w.Write(b.data)
+	if err == nil && n != len(b.data) {
+		err = io.ErrShortWrite
+	}
+
+	return int64(n), err
+}
+
+const minRead = 512
+
+// ReadFrom appends the data read from r until EOF. A shared buffer is
+// copied before the first read, a buffer it owns is grown in place.
+func (b *COWBuffer) ReadFrom(r io.Reader) (int64, error) {
+	if !b.ref.valid() {
+		return 0, ErrBufferClosed
+	}
+
+	var total int64
+	for {
+		if b.ref.shared() {
+			b.detach(len(b.data) + minRead)
+		} else if len(b.data) == cap(b.data) {
+			b.data = slices.Grow(b.data, minRead)
+			b.pooled = b.pool != nil
+		}
+
+		n, err := r.Read(b.data[len(b.data):cap(b.data)])
+		b.data = b.data[:len(b.data)+n]
+		total += int64(n)
+
+		if err == io.EOF {
+			return total, nil
+		}
+
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// COWReader reads a snapshot of a COWBuffer: updates of the buffer made
+// after NewCOWReader are not visible to it. COWReader is not thread-safe.
+type COWReader struct {
+	snapshot *COWBuffer
+	offset   int
+}
+
+// NewCOWReader holds a reference to the data of b until Close.
+func NewCOWReader(b *COWBuffer) *COWReader {
+	snapshot := b.Clone()
+	if snapshot == nil {
+		snapshot = &COWBuffer{}
+	}
+
+	return &COWReader{snapshot: snapshot}
+}
+
+func (r *COWReader) Read(p []byte) (int, error) {
+	if r.offset >= len(r.snapshot.data) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.snapshot.data[r.offset:])
+	r.offset += n
+
+	return n, nil
+}
+
+func (r *COWReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	if off >= int64(len(r.snapshot.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.snapshot.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (r *COWReader) WriteTo(w io.Writer) (int64, error) {
+	if r.offset >= len(r.snapshot.data) {
+		return 0, nil
+	}
+
+	n, err := w.Write(r.snapshot.data[r.offset:])
+	r.offset += n
+
+	if err == nil && r.offset != len(r.snapshot.data) {
+		err = io.ErrShortWrite
+	}
+
+	return int64(n), err
+}
+
+// Len returns the number of unread bytes.
+func (r *COWReader) Len() int {
+	return max(len(r.snapshot.data)-r.offset, 0)
+}
+
+func (r *COWReader) Close() {
+	r.snapshot.Close()
+	r.offset = 0
+}
+
+func TestCOWReader(t *testing.T) {
+	data := []byte("hello, world")
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	reader := NewCOWReader(buffer)
+	defer reader.Close()
+
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+	assert.True(t, buffer.Update(0, 'H'))
+	assert.Equal(t, "Hello, world", string(buffer.Bytes()))
+
+	conformance := NewCOWReader(buffer)
+	assert.NoError(t, iotest.TestReader(conformance, []byte("Hello, world")))
+	conformance.Close()
+
+	p := make([]byte, 5)
+	n, err := reader.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", string(p))
+	assert.Equal(t, 7, reader.Len())
+
+	n, err = reader.ReadAt(p, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "world", string(p))
+
+	n, err = reader.ReadAt(p, 10)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 2, n)
+
+	_, err = reader.ReadAt(p, -1)
+	assert.ErrorIs(t, err, ErrNegativeOffset)
+
+	var sb strings.Builder
+	written, err := reader.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), written)
+	assert.Equal(t, ", world", sb.String())
+	assert.Zero(t, reader.Len())
+
+	_, err = reader.Read(p)
+	assert.ErrorIs(t, err, io.EOF)
+
+	reader.Close()
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+
+	closed := NewCOWReader(&COWBuffer{})
+	_, err = closed.Read(p)
+	assert.ErrorIs(t, err, io.EOF)
+	closed.Close()
+}
+
+func TestCOWBuffer_WriteTo(t *testing.T) {
+	buffer := NewCOWBuffer([]byte("abc"))
+	defer buffer.Close()
+
+	var out bytes.Buffer
+	n, err := buffer.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, "abc", out.String())
+
+	empty := NewCOWBuffer(nil)
+	n, err = empty.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	empty.Close()
+	n, err = empty.WriteTo(&out)
+	assert.ErrorIs(t, err, ErrBufferClosed)
+	assert.Zero(t, n)
+	assert.Equal(t, "abc", out.String())
+}
+
+func TestCOWBuffer_ReadFrom(t *testing.T) {
+	data := []byte("head:")
+	buffer := NewCOWBuffer(data)
+	defer buffer.Close()
+
+	clone := buffer.Clone()
+	defer clone.Close()
+
+	payload := strings.Repeat("x", 3*minRead)
+	n, err := buffer.ReadFrom(iotest.OneByteReader(strings.NewReader(payload)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), n)
+	assert.Equal(t, "head:"+payload, string(buffer.Bytes()))
+	assert.Equal(t, "head:", string(clone.Bytes()))
+	assert.Equal(t, "head:", string(data))
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+	assert.Equal(t, int64(1), clone.ref.refs.Load())
+
+	// the sole owner reads into its spare capacity
+	assert.True(t, buffer.Truncate(5))
+	owned := unsafe.SliceData(buffer.data)
+	n, err = buffer.ReadFrom(strings.NewReader("tail"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.Equal(t, "head:tail", string(buffer.Bytes()))
+	assert.Same(t, owned, unsafe.SliceData(buffer.data))
+
+	failure := errors.New("failure")
+	n, err = clone.ReadFrom(io.MultiReader(strings.NewReader("ok"), iotest.ErrReader(failure)))
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, "head:ok", string(clone.Bytes()))
+
+	var closed COWBuffer
+	_, err = closed.ReadFrom(strings.NewReader("data"))
+	assert.ErrorIs(t, err, ErrBufferClosed)
+}
+
+func TestCOWBuffer_Pool(t *testing.T) {
+	var pool COWPool
+
+	data := []byte("abcd")
+	buffer := NewCOWBuffer(data, WithPool(&pool))
+	defer buffer.Close()
+
+	for range 3 {
+		clone := buffer.Clone()
+		assert.Same(t, &pool, clone.pool)
+		assert.False(t, clone.pooled)
+
+		assert.True(t, clone.Update(0, 'x'))
+		assert.Equal(t, "xbcd", string(clone.Bytes()))
+		assert.True(t, clone.pooled)
+
+		view := clone.Slice(1, 3)
+		assert.False(t, view.pooled)
+		clone.Close()
+		assert.Equal(t, "bc", string(view.Bytes()))
+		view.Close()
+	}
+
+	assert.Equal(t, "abcd", string(buffer.Bytes()))
+	assert.Equal(t, "abcd", string(data))
+
+	// data that was never copied doesn't belong to the pool
+	assert.True(t, buffer.Update(0, 'y'))
+	assert.False(t, buffer.pooled)
+	assert.Same(t, unsafe.SliceData(data), unsafe.SliceData(buffer.data))
+}
+
+func BenchmarkCOWBuffer_UpdateAllocator(b *testing.B) {
+	const size = 64 << 10
+
+	var pool COWPool
+	benchmarks := []struct {
+		name string
+		opts []COWOption
+	}{
+		{name: "make"},
+		{name: "pool", opts: []COWOption{WithPool(&pool)}},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			buffer := NewCOWBuffer(make([]byte, size), bench.opts...)
+			defer buffer.Close()
+
+			b.ReportAllocs()
+
+			i := 0
+			for b.Loop() {
+				clone := buffer.Clone()
+				clone.Update(i%size, 'x')
+				clone.Close()
+				i++
+			}
+		})
+	}
+}
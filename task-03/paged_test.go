@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go paged_test.go
+// go test -bench=. -benchmem homework_test.go paged_test.go
+
+type cowPage struct {
+	data   []byte
+	refs   *atomic.Int64
+	pinned bool
+}
+
+func newCOWPage(data []byte) cowPage {
+	refs := new(atomic.Int64)
+	refs.Store(1)
+
+	return cowPage{data: data, refs: refs}
+}
+
+// PagedCOWBuffer splits its data into fixed-size pages with their own reference
+// counters, so an update copies only the page it touches.
+// PagedCOWBuffer is not thread-safe.
+type PagedCOWBuffer struct {
+	pages    []cowPage
+	pageSize int
+	size     int
+	cleanup  runtime.Cleanup
+}
+
+func newPagedCOWBuffer(pages []cowPage, pageSize, size int) *PagedCOWBuffer {
+	b := &PagedCOWBuffer{
+		pages:    pages,
+		pageSize: pageSize,
+		size:     size,
+	}
+
+	// pages shares its backing array with b.pages, so the cleanup
+	// releases the pages that Update has replaced as well.
+	b.cleanup = runtime.AddCleanup(b, releasePages, pages)
+
+	return b
+}
+
+// NewPagedCOWBuffer shares data without copying; pageSize is at least 1.
+func NewPagedCOWBuffer(data []byte, pageSize int) *PagedCOWBuffer {
+	pageSize = max(pageSize, 1)
+
+	pages := make([]cowPage, 0, (len(data)+pageSize-1)/pageSize)
+	for start := 0; start < len(data); start += pageSize {
+		end := min(start+pageSize, len(data))
+		pages = append(pages, newCOWPage(data[start:end:end]))
+	}
+
+	return newPagedCOWBuffer(pages, pageSize, len(data))
+}
+
+func (b *PagedCOWBuffer) Clone() *PagedCOWBuffer {
+	if b.pages == nil {
+		return nil
+	}
+
+	pages := make([]cowPage, len(b.pages))
+	copy(pages, b.pages)
+
+	for _, page := range pages {
+		page.refs.Add(1)
+	}
+
+	return newPagedCOWBuffer(pages, b.pageSize, b.size)
+}
+
+func (b *PagedCOWBuffer) Close() {
+	b.cleanup.Stop()
+	runtime.KeepAlive(b)
+
+	releasePages(b.pages)
+
+	b.pages = nil
+	b.size = 0
+}
+
+func releasePages(pages []cowPage) {
+	for _, page := range pages {
+		page.refs.Add(-1)
+	}
+}
+
+func (b *PagedCOWBuffer) Update(index int, value byte) bool {
+	if index < 0 || index >= b.size {
+		return false
+	}
+
+	page := &b.pages[index/b.pageSize]
+	if page.refs.Load() > 1 {
+		page.refs.Add(-1)
+		*page = newCOWPage(bytes.Clone(page.data))
+	}
+
+	page.data[index%b.pageSize] = value
+
+	return true
+}
+
+// String returns the data without copying when it fits into a single page,
+// pinning that page like COWBuffer.String, and concatenates the pages otherwise.
+func (b *PagedCOWBuffer) String() string {
+	switch len(b.pages) {
+	case 0:
+		return ""
+	case 1:
+		page := &b.pages[0]
+		if !page.pinned {
+			page.refs.Add(1)
+			page.pinned = true
+		}
+
+		return *(*string)(unsafe.Pointer(&page.data))
+	}
+
+	var sb strings.Builder
+	sb.Grow(b.size)
+
+	for _, page := range b.pages {
+		sb.Write(page.data)
+	}
+
+	return sb.String()
+}
+
+func (b *PagedCOWBuffer) Len() int {
+	return b.size
+}
+
+func TestPagedCOWBuffer(t *testing.T) {
+	data := []byte("abcdefghij")
+	buffer := NewPagedCOWBuffer(data, 4)
+	defer buffer.Close()
+
+	assert.Len(t, buffer.pages, 3)
+	assert.Equal(t, 10, buffer.Len())
+	assert.Equal(t, "abcdefghij", buffer.String())
+
+	copy1 := buffer.Clone()
+	copy2 := buffer.Clone()
+
+	for i := range buffer.pages {
+		assert.Same(t, unsafe.SliceData(buffer.pages[i].data), unsafe.SliceData(copy1.pages[i].data))
+		assert.Equal(t, int64(3), buffer.pages[i].refs.Load())
+	}
+
+	assert.True(t, copy1.Update(5, 'X'))
+	assert.False(t, copy1.Update(10, 'X'))
+	assert.False(t, copy1.Update(-1, 'X'))
+
+	assert.Equal(t, "abcdeXghij", copy1.String())
+	assert.Equal(t, "abcdefghij", buffer.String())
+	assert.Equal(t, "abcdefghij", copy2.String())
+	assert.Equal(t, "abcdefghij", string(data))
+
+	// only the touched page diverges
+	assert.Same(t, unsafe.SliceData(buffer.pages[0].data), unsafe.SliceData(copy1.pages[0].data))
+	assert.NotSame(t, unsafe.SliceData(buffer.pages[1].data), unsafe.SliceData(copy1.pages[1].data))
+	assert.Same(t, unsafe.SliceData(buffer.pages[2].data), unsafe.SliceData(copy1.pages[2].data))
+
+	assert.Equal(t, int64(2), buffer.pages[1].refs.Load())
+	assert.Equal(t, int64(1), copy1.pages[1].refs.Load())
+	assert.Equal(t, int64(3), buffer.pages[0].refs.Load())
+
+	copy2.Close()
+	copy1.Close()
+
+	// 1 reference - don't need to copy page during update
+	previous := unsafe.SliceData(buffer.pages[2].data)
+	assert.True(t, buffer.Update(9, 'Z'))
+	assert.Same(t, previous, unsafe.SliceData(buffer.pages[2].data))
+	assert.Equal(t, "abcdefghiZ", buffer.String())
+}
+
+func TestPagedCOWBuffer_SinglePage(t *testing.T) {
+	data := []byte("abc")
+	buffer := NewPagedCOWBuffer(data, 0)
+	defer buffer.Close()
+
+	assert.Len(t, buffer.pages, 3)
+
+	single := NewPagedCOWBuffer(data, 16)
+	defer single.Close()
+
+	assert.Len(t, single.pages, 1)
+	assert.Same(t, unsafe.SliceData(data), unsafe.StringData(single.String()))
+}
+
+func TestPagedCOWBuffer_NilSafety(t *testing.T) {
+	var b PagedCOWBuffer
+
+	assert.Equal(t, "", b.String())
+	assert.False(t, b.Update(0, 'a'))
+	assert.Nil(t, b.Clone())
+
+	assert.NotPanics(t, func() {
+		b.Close()
+		b.Close()
+	})
+
+	empty := NewPagedCOWBuffer(nil, 4)
+	assert.Equal(t, "", empty.String())
+	assert.False(t, empty.Update(0, 'a'))
+}
+
+func TestPagedCOWBuffer_CloseClose(t *testing.T) {
+	buffer := NewPagedCOWBuffer([]byte("abcdef"), 2)
+	clone := buffer.Clone()
+	refs := buffer.pages[0].refs
+
+	buffer.Close()
+	buffer.Close()
+
+	assert.Equal(t, int64(1), refs.Load())
+	assert.Nil(t, buffer.pages)
+	assert.Equal(t, "abcdef", clone.String())
+
+	clone.Close()
+	assert.Equal(t, int64(0), refs.Load())
+}
+
+const benchmarkPayloadSize = 4 << 20
+
+func BenchmarkCOWBuffer_UpdateShared(b *testing.B) {
+	buffer := NewCOWBuffer(make([]byte, benchmarkPayloadSize))
+	defer buffer.Close()
+
+	b.ReportAllocs()
+
+	i := 0
+	for b.Loop() {
+		clone := buffer.Clone()
+		clone.Update(i%benchmarkPayloadSize, 'x')
+		clone.Close()
+		i++
+	}
+}
+
+func BenchmarkPagedCOWBuffer_UpdateShared(b *testing.B) {
+	buffer := NewPagedCOWBuffer(make([]byte, benchmarkPayloadSize), 4096)
+	defer buffer.Close()
+
+	b.ReportAllocs()
+
+	i := 0
+	for b.Loop() {
+		clone := buffer.Clone()
+		clone.Update(i%benchmarkPayloadSize, 'x')
+		clone.Close()
+		i++
+	}
+}
+
+func TestPagedCOWBuffer_Cleanup(t *testing.T) {
+	buffer := NewPagedCOWBuffer([]byte("abcdef"), 2)
+	defer buffer.Close()
+
+	refs := buffer.pages[0].refs
+
+	func() {
+		clone := buffer.Clone()
+		clone.Update(0, 'x')
+	}()
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return refs.Load() == 1 && buffer.pages[2].refs.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestPagedCOWBuffer_StringIsImmutable(t *testing.T) {
+	buffer := NewPagedCOWBuffer([]byte("abc"), 4)
+	defer buffer.Close()
+
+	str := buffer.String()
+	assert.Equal(t, int64(2), buffer.pages[0].refs.Load())
+	assert.Equal(t, str, buffer.String())
+	assert.Equal(t, int64(2), buffer.pages[0].refs.Load())
+
+	assert.True(t, buffer.Update(0, 'x'))
+	assert.Equal(t, "abc", str)
+	assert.False(t, buffer.pages[0].pinned)
+	assert.Equal(t, int64(1), buffer.pages[0].refs.Load())
+	assert.Equal(t, "xbc", buffer.String())
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -v homework_test.go rope_test.go
+
+// COWRope concatenates buffers without copying them. It holds its own
+// reference to every part, so the parts stay immutable while the rope is open.
+// COWRope is not thread-safe.
+type COWRope struct {
+	parts []*COWBuffer
+	size  int
+}
+
+// NewCOWRope clones the given buffers; the caller keeps its own references.
+// Closed and empty buffers are skipped.
+func NewCOWRope(parts ...*COWBuffer) *COWRope {
+	r := &COWRope{}
+	for _, part := range parts {
+		r.Append(part)
+	}
+
+	return r
+}
+
+// Append adds a clone of b to the end of the rope.
+func (r *COWRope) Append(b *COWBuffer) {
+	if b == nil || len(b.data) == 0 {
+		return
+	}
+
+	if part := b.Clone(); part != nil {
+		r.parts = append(r.parts, part)
+		r.size += len(part.data)
+	}
+}
+
+func (r *COWRope) Close() {
+	for _, part := range r.parts {
+		part.Close()
+	}
+
+	r.parts = nil
+	r.size = 0
+}
+
+func (r *COWRope) Len() int {
+	return r.size
+}
+
+// At returns the byte at index. It is linear in the number of parts.
+func (r *COWRope) At(index int) (byte, bool) {
+	if index < 0 || index >= r.size {
+		return 0, false
+	}
+
+	for _, part := range r.parts {
+		if index < len(part.data) {
+			return part.data[index], true
+		}
+
+		index -= len(part.data)
+	}
+
+	return 0, false
+}
+
+// Slice returns a rope over the bytes in [from, to) made of views of the
+// parts, so it doesn't copy either. It returns nil for an invalid range.
+func (r *COWRope) Slice(from, to int) *COWRope {
+	if from < 0 || from > to || to > r.size {
+		return nil
+	}
+
+	slice := &COWRope{}
+	for _, part := range r.parts {
+		if start, end := max(from, 0), min(to, len(part.data)); start < end {
+			view := part.Slice(start, end)
+			slice.parts = append(slice.parts, view)
+			slice.size += len(view.data)
+		}
+
+		from -= len(part.data)
+		to -= len(part.data)
+	}
+
+	return slice
+}
+
+// Flatten returns the rope as a single buffer. A rope of one part
+// is flattened without copying by cloning that part.
+func (r *COWRope) Flatten() *COWBuffer {
+	if len(r.parts) == 1 {
+		return r.parts[0].Clone()
+	}
+
+	data := make([]byte, 0, r.size)
+	for _, part := range r.parts {
+		data = append(data, part.data...)
+	}
+
+	return NewCOWBuffer(data)
+}
+
+func (r *COWRope) String() string {
+	if len(r.parts) == 1 {
+		return r.parts[0].String()
+	}
+
+	var sb strings.Builder
+	sb.Grow(r.size)
+
+	for _, part := range r.parts {
+		sb.Write(part.data)
+	}
+
+	return sb.String()
+}
+
+func TestCOWRope(t *testing.T) {
+	hello := NewCOWBuffer([]byte("hello"))
+	defer hello.Close()
+
+	world := NewCOWBuffer([]byte("world"))
+	defer world.Close()
+
+	space := NewCOWBuffer([]byte(", "))
+	rope := NewCOWRope(hello, space, world, NewCOWBuffer(nil), nil)
+	defer rope.Close()
+
+	space.Close()
+
+	assert.Len(t, rope.parts, 3)
+	assert.Equal(t, 12, rope.Len())
+	assert.Equal(t, "hello, world", rope.String())
+	assert.Equal(t, int64(2), hello.ref.refs.Load())
+	assert.Same(t, unsafe.SliceData(hello.data), unsafe.SliceData(rope.parts[0].data))
+
+	b, ok := rope.At(7)
+	assert.True(t, ok)
+	assert.Equal(t, byte('w'), b)
+
+	_, ok = rope.At(12)
+	assert.False(t, ok)
+	_, ok = rope.At(-1)
+	assert.False(t, ok)
+
+	// updating a part copies it, the rope keeps the original bytes
+	assert.True(t, hello.Update(0, 'H'))
+	assert.Equal(t, "Hello", string(hello.Bytes()))
+	assert.Equal(t, "hello, world", rope.String())
+
+	rope.Append(NewCOWBuffer([]byte("!")))
+	assert.Equal(t, "hello, world!", rope.String())
+
+	flat := rope.Flatten()
+	defer flat.Close()
+
+	assert.Equal(t, "hello, world!", string(flat.Bytes()))
+	assert.Equal(t, int64(1), flat.ref.refs.Load())
+
+	assert.True(t, flat.Update(0, 'j'))
+	assert.Equal(t, "hello, world!", rope.String())
+}
+
+func TestCOWRope_Slice(t *testing.T) {
+	first := NewCOWBuffer([]byte("abc"))
+	second := NewCOWBuffer([]byte("def"))
+	third := NewCOWBuffer([]byte("ghi"))
+
+	rope := NewCOWRope(first, second, third)
+	first.Close()
+	second.Close()
+	third.Close()
+
+	assert.Nil(t, rope.Slice(-1, 2))
+	assert.Nil(t, rope.Slice(5, 4))
+	assert.Nil(t, rope.Slice(0, 10))
+
+	slice := rope.Slice(2, 7)
+	assert.Equal(t, "cdefg", slice.String())
+	assert.Len(t, slice.parts, 3)
+	assert.Same(t, unsafe.SliceData(rope.parts[1].data), unsafe.SliceData(slice.parts[1].data))
+	assert.Equal(t, int64(2), rope.parts[0].ref.refs.Load())
+
+	inner := rope.Slice(4, 5)
+	assert.Equal(t, "e", inner.String())
+	assert.Len(t, inner.parts, 1)
+
+	// a single part is flattened by sharing it
+	flat := inner.Flatten()
+	assert.Same(t, unsafe.SliceData(inner.parts[0].data), unsafe.SliceData(flat.data))
+	flat.Close()
+	inner.Close()
+
+	assert.Empty(t, rope.Slice(3, 3).parts)
+	assert.Empty(t, rope.Slice(1, 1).parts)
+	assert.Empty(t, rope.Slice(9, 9).parts)
+	assert.Equal(t, int64(2), rope.parts[0].ref.refs.Load())
+
+	rope.Close()
+	assert.Equal(t, int64(1), slice.parts[0].ref.refs.Load())
+	assert.Equal(t, "cdefg", slice.String())
+
+	slice.Close()
+	assert.Zero(t, slice.Len())
+	assert.Equal(t, "", slice.String())
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -race -v homework_test.go sync_test.go
+
+// SyncCOWBuffer is a COWBuffer with an atomic reference counter, so different
+// handles sharing the same data may be cloned, updated and closed concurrently.
+// Clone only reads its handle, so several goroutines may clone the same handle
+// at once; any other use of a single handle must not be concurrent.
+type SyncCOWBuffer struct {
+	data   []byte
+	ref    cowRef
+	pinned bool
+}
+
+func newSyncCOWBuffer(data []byte, refs *atomic.Int64, pinned bool) *SyncCOWBuffer {
+	b := &SyncCOWBuffer{
+		data:   data,
+		pinned: pinned,
+	}
+
+	b.ref.attach(refs)
+
+	return b
+}
+
+func NewSyncCOWBuffer(data []byte) *SyncCOWBuffer {
+	return newSyncCOWBuffer(data, nil, false)
+}
+
+func (b *SyncCOWBuffer) Clone() *SyncCOWBuffer {
+	if !b.ref.valid() {
+		return nil
+	}
+
+	return newSyncCOWBuffer(b.data, b.ref.refs, b.pinned)
+}
+
+func (b *SyncCOWBuffer) Close() {
+	b.ref.release()
+	b.data = nil
+}
+
+func (b *SyncCOWBuffer) Update(index int, value byte) bool {
+	if !b.ref.valid() || len(b.data) == 0 {
+		return false
+	}
+
+	if index < 0 || index >= len(b.data) {
+		return false
+	}
+
+	// The copy must be taken before releasing the shared reference:
+	// once it is released, the last owner may start writing in place.
+	if b.ref.shared() {
+		newData := make([]byte, len(b.data))
+		copy(newData, b.data)
+
+		b.data = newData
+		b.ref.detach()
+		b.pinned = false
+	}
+
+	b.data[index] = value
+
+	return true
+}
+
+// String returns the data without copying and pins it like COWBuffer.String,
+// so the string stays immutable while other handles keep updating.
+func (b *SyncCOWBuffer) String() string {
+	if b.ref.valid() && !b.pinned && len(b.data) > 0 {
+		b.ref.pin()
+		b.pinned = true
+	}
+
+	return *(*string)(unsafe.Pointer(&b.data))
+}
+
+func TestSyncCOWBuffer(t *testing.T) {
+	data := []byte{'a', 'b', 'c', 'd'}
+	buffer := NewSyncCOWBuffer(data)
+	defer buffer.Close()
+
+	copy1 := buffer.Clone()
+	copy2 := buffer.Clone()
+
+	assert.Equal(t, int64(3), buffer.ref.refs.Load())
+	assert.Equal(t, unsafe.SliceData(buffer.data), unsafe.SliceData(copy1.data))
+	assert.Equal(t, unsafe.SliceData(copy1.data), unsafe.SliceData(copy2.data))
+
+	assert.True(t, copy1.Update(0, 'g'))
+	assert.False(t, copy1.Update(4, 'g'))
+
+	assert.Equal(t, "gbcd", string(copy1.data))
+	assert.Equal(t, "abcd", string(buffer.data))
+	assert.Equal(t, "abcd", string(copy2.data))
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+	assert.Equal(t, int64(1), copy1.ref.refs.Load())
+
+	copy2.Close()
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+
+	previous := buffer.data
+	assert.True(t, buffer.Update(1, 'x'))
+
+	// 1 reference - don't need to copy buffer during update
+	assert.Equal(t, unsafe.SliceData(previous), unsafe.SliceData(buffer.data))
+	assert.Equal(t, "axcd", string(buffer.data))
+
+	copy1.Close()
+}
+
+func TestSyncCOWBuffer_NilSafety(t *testing.T) {
+	var b SyncCOWBuffer
+
+	assert.Equal(t, "", b.String())
+	assert.False(t, b.Update(0, 'a'))
+
+	assert.NotPanics(t, func() {
+		b.Close()
+		b.Close()
+	})
+
+	assert.Nil(t, b.Clone())
+}
+
+func TestSyncCOWBuffer_Concurrent(t *testing.T) {
+	const goroutines = 32
+	const rounds = 200
+
+	original := bytes.Repeat([]byte{'a'}, 64)
+	buffer := NewSyncCOWBuffer(bytes.Clone(original))
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for round := range rounds {
+				clone := buffer.Clone()
+				nested := clone.Clone()
+
+				if string(clone.data) != string(original) {
+					t.Errorf("shared buffer was mutated: %q", string(clone.data))
+				}
+
+				value := byte('b' + (g+round)%20)
+				clone.Update(g, value)
+				clone.Update(g+1, value)
+
+				if clone.data[g] != value || clone.data[g+1] != value {
+					t.Errorf("update lost: %q", string(clone.data))
+				}
+
+				if string(nested.data) != string(original) {
+					t.Errorf("nested clone sees mutation: %q", string(nested.data))
+				}
+
+				clone.Close()
+				nested.Close()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+	assert.Equal(t, string(original), string(buffer.data))
+
+	previous := unsafe.SliceData(buffer.data)
+	assert.True(t, buffer.Update(0, 'z'))
+	assert.Equal(t, previous, unsafe.SliceData(buffer.data))
+
+	buffer.Close()
+}
+
+func TestSyncCOWBuffer_LastCloserWritesInPlace(t *testing.T) {
+	const goroutines = 16
+
+	for range 50 {
+		buffer := NewSyncCOWBuffer([]byte("shared"))
+		clones := make([]*SyncCOWBuffer, goroutines)
+		for i := range clones {
+			clones[i] = buffer.Clone()
+		}
+
+		buffer.Close()
+
+		var inPlace atomic.Int64
+		var wg sync.WaitGroup
+
+		for i, clone := range clones {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				shared := unsafe.SliceData(clone.data)
+				clone.Update(0, byte('A'+i))
+
+				if unsafe.SliceData(clone.data) == shared {
+					inPlace.Add(1)
+				}
+
+				if clone.data[0] != byte('A'+i) || string(clone.data[1:]) != "hared" {
+					t.Errorf("unexpected data: %q", string(clone.data))
+				}
+
+				clone.Close()
+			}()
+		}
+
+		wg.Wait()
+
+		assert.LessOrEqual(t, inPlace.Load(), int64(1))
+	}
+}
+
+func TestSyncCOWBuffer_StringIsImmutable(t *testing.T) {
+	buffer := NewSyncCOWBuffer([]byte("abc"))
+	defer buffer.Close()
+
+	str := buffer.String()
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+	assert.Equal(t, str, buffer.String())
+	assert.Equal(t, int64(2), buffer.ref.refs.Load())
+
+	assert.True(t, buffer.Update(0, 'x'))
+	assert.Equal(t, "abc", str)
+	assert.Equal(t, "xbc", string(buffer.data))
+	assert.Equal(t, int64(1), buffer.ref.refs.Load())
+
+	clone := buffer.Clone()
+	cloned := clone.String()
+	clone.Close()
+
+	assert.True(t, buffer.Update(1, 'y'))
+	assert.Equal(t, "xbc", cloned)
+	assert.Equal(t, "xyc", string(buffer.data))
+}
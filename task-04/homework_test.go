@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"math/rand"
 	"reflect"
 
@@ -13,12 +14,75 @@ import (
 // go test -v homework_test.go
 
 type node[K constraints.Ordered, V any] struct {
-	key   K
-	value V
-	left  *node[K, V]
-	right *node[K, V]
+	key    K
+	value  V
+	left   *node[K, V]
+	right  *node[K, V]
+	height int
 }
 
+func (n *node[K, V]) getHeight() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.height
+}
+
+func (n *node[K, V]) updateHeight() {
+	n.height = max(n.left.getHeight(), n.right.getHeight()) + 1
+}
+
+func (n *node[K, V]) balance() int {
+	return n.right.getHeight() - n.left.getHeight()
+}
+
+func (n *node[K, V]) rotateLeft() *node[K, V] {
+	right := n.right
+	n.right = right.left
+	right.left = n
+
+	n.updateHeight()
+	right.updateHeight()
+
+	return right
+}
+
+func (n *node[K, V]) rotateRight() *node[K, V] {
+	left := n.left
+	n.left = left.right
+	left.right = n
+
+	n.updateHeight()
+	left.updateHeight()
+
+	return left
+}
+
+// rebalance restores the AVL invariant of n, whose subtrees are balanced
+// and differ in height by at most 2, and returns the new root of the subtree.
+func (n *node[K, V]) rebalance() *node[K, V] {
+	n.updateHeight()
+
+	switch balance := n.balance(); {
+	case balance > 1:
+		if n.right.balance() < 0 {
+			n.right = n.right.rotateRight()
+		}
+
+		return n.rotateLeft()
+	case balance < -1:
+		if n.left.balance() > 0 {
+			n.left = n.left.rotateLeft()
+		}
+
+		return n.rotateRight()
+	}
+
+	return n
+}
+
+// OrderedMap is an AVL tree, so its height stays logarithmic in its size.
 // OrderedMap is not thread-safe.
 type OrderedMap[K constraints.Ordered, V any] struct {
 	root *node[K, V]
@@ -30,38 +94,72 @@ func NewOrderedMap[K constraints.Ordered, V any]() OrderedMap[K, V] {
 }
 
 func (m *OrderedMap[K, V]) Insert(key K, value V) {
-	nodeRef := m.findNodeRef(key)
-	if *nodeRef == nil {
-		*nodeRef = &node[K, V]{key: key, value: value}
+	m.root = m.insert(m.root, key, value)
+}
+
+func (m *OrderedMap[K, V]) insert(n *node[K, V], key K, value V) *node[K, V] {
+	if n == nil {
 		m.size++
+		return &node[K, V]{key: key, value: value, height: 1}
+	}
+
+	if key < n.key {
+		n.left = m.insert(n.left, key, value)
+	} else if key > n.key {
+		n.right = m.insert(n.right, key, value)
 	} else {
-		(*nodeRef).value = value
+		n.value = value
+		return n
 	}
+
+	return n.rebalance()
 }
 
 func (m *OrderedMap[K, V]) Erase(key K) {
-	nodeRef := m.findNodeRef(key)
-	if *nodeRef == nil {
-		return
+	m.root = m.erase(m.root, key)
+}
+
+func (m *OrderedMap[K, V]) erase(n *node[K, V], key K) *node[K, V] {
+	if n == nil {
+		return nil
 	}
 
-	if (*nodeRef).left == nil {
-		*nodeRef = (*nodeRef).right
-	} else if (*nodeRef).right == nil {
-		*nodeRef = (*nodeRef).left
+	if key < n.key {
+		n.left = m.erase(n.left, key)
+	} else if key > n.key {
+		n.right = m.erase(n.right, key)
 	} else {
-		minLeftNodeRef := &(*nodeRef).right
-		for (*minLeftNodeRef).left != nil {
-			minLeftNodeRef = &(*minLeftNodeRef).left
+		m.size--
+
+		if n.left == nil {
+			return n.right
 		}
 
-		(*nodeRef).key = (*minLeftNodeRef).key
-		(*nodeRef).value = (*minLeftNodeRef).value
+		if n.right == nil {
+			return n.left
+		}
+
+		minRightNode := n.right
+		for minRightNode.left != nil {
+			minRightNode = minRightNode.left
+		}
 
-		*minLeftNodeRef = (*minLeftNodeRef).right
+		n.key = minRightNode.key
+		n.value = minRightNode.value
+		n.right = eraseMin(n.right)
 	}
 
-	m.size--
+	return n.rebalance()
+}
+
+func eraseMin[K constraints.Ordered, V any](n *node[K, V]) *node[K, V] {
+	if n.left == nil {
+		return n.right
+	}
+
+	n.left = eraseMin(n.left)
+
+	return n.rebalance()
 }
 
 func (m *OrderedMap[K, V]) Contains(key K) bool {
@@ -153,6 +251,72 @@ func TestCircularQueue(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(expectedKeys, keys))
 }
 
+// checkBalanced verifies the heights and the AVL invariant of every node.
+func checkBalanced[K constraints.Ordered, V any](t *testing.T, n *node[K, V]) int {
+	t.Helper()
+
+	if n == nil {
+		return 0
+	}
+
+	left := checkBalanced(t, n.left)
+	right := checkBalanced(t, n.right)
+
+	if n.height != max(left, right)+1 {
+		t.Errorf("wrong height of %v: expected %d, got %d", n.key, max(left, right)+1, n.height)
+	}
+
+	if right-left < -1 || right-left > 1 {
+		t.Errorf("unbalanced node %v: left height %d, right height %d", n.key, left, right)
+	}
+
+	return n.height
+}
+
+// maxAVLHeight is the height bound of an AVL tree with size nodes.
+func maxAVLHeight(size int) int {
+	return int(1.4405*math.Log2(float64(size+2)) - 0.3277)
+}
+
+func TestOrderedMapMonotonicInsert(t *testing.T) {
+	const size = 1 << 16
+
+	tests := map[string]func(i int) int{
+		"increasing": func(i int) int { return i },
+		"decreasing": func(i int) int { return size - i },
+	}
+
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := NewOrderedMap[int, int]()
+
+			for i := range size {
+				data.Insert(key(i), i)
+			}
+
+			assert.Equal(t, size, data.Size())
+			assert.LessOrEqual(t, data.root.height, maxAVLHeight(size))
+			checkBalanced(t, data.root)
+
+			for i := range size / 2 {
+				data.Erase(key(i))
+			}
+
+			assert.Equal(t, size/2, data.Size())
+			assert.LessOrEqual(t, data.root.height, maxAVLHeight(size/2))
+			assert.False(t, data.Contains(key(0)))
+			assert.True(t, data.Contains(key(size-1)))
+			checkBalanced(t, data.root)
+
+			prev := math.MinInt
+			data.ForEach(func(k, _ int) {
+				assert.Less(t, prev, k)
+				prev = k
+			})
+		})
+	}
+}
+
 // go test -v homework_test.go -fuzz=FuzzOrderedMap
 func FuzzOrderedMap(f *testing.F) {
 	const eraseLen = 3
@@ -180,6 +344,8 @@ func FuzzOrderedMap(f *testing.F) {
 		if count != expectedLen {
 			t.Errorf("expected %d unique keys, got %d from ForEach", expectedLen, count)
 		}
+
+		checkBalanced(t, m.root)
 	}
 
 	f.Add(int64(0), int64(0))